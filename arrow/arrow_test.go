@@ -0,0 +1,217 @@
+package arrow
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/hektorinho/gospss"
+)
+
+// texts returns the values of a as strings, "(null)" for nulls.
+func texts(a arrow.Array) []string {
+	s := make([]string, a.Len())
+	for i := range s {
+		s[i] = a.ValueStr(i)
+	}
+	return s
+}
+
+// dictionaryOf returns the dictionary of the dictionary array a as
+// strings.
+func dictionaryOf(a arrow.Array) []string {
+	return texts(a.(*array.Dictionary).Dictionary())
+}
+
+func TestReader(t *testing.T) {
+	when := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	d := &gospss.Dictionary{
+		FileLabel:   "gospss arrow test",
+		Compression: gospss.Bytecode,
+		Variables: []*gospss.Variable{
+			{Name: "Score", Label: "Satisfaction score", Numeric: true, Print: gospss.Format{Type: gospss.FormatF, Width: 8, Decimals: 2},
+				Measure: gospss.MeasureOrdinal, ValueLabels: []*gospss.ValueLabel{{Key: 1.0, Value: "Low"}, {Key: 2.0, Value: "High"}}},
+			{Name: "City", Width: 20, ValueLabels: []*gospss.ValueLabel{{Key: "Oslo", Value: "Capital"}}},
+			{Name: "When", Numeric: true, Print: gospss.Format{Type: gospss.FormatDATETIME, Width: 20}},
+			{Name: "Day", Numeric: true, Print: gospss.Format{Type: gospss.FormatDATE, Width: 11}},
+			{Name: "Took", Numeric: true, Print: gospss.Format{Type: gospss.FormatTIME, Width: 11, Decimals: 2}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := gospss.NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]gospss.Row{
+		{1.0, "Stockholm", when, when, 90*time.Minute + 1500*time.Millisecond},
+		{2.5, "Oslo", nil, nil, nil},
+		{math.NaN(), "Malmö", when.AddDate(-100, 0, 0), when.AddDate(-100, 0, 0), 25 * time.Hour},
+		{2.0, "Göteborg", when, when, time.Second},
+		{3.0, "Oslo", when, when, time.Second},
+	}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	r, err := gospss.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	ar := NewReader(r, WithBatchSize(3), WithDictionaries(), WithAllocator(mem))
+	schema := ar.Schema()
+	want := arrow.NewSchema([]arrow.Field{
+		{Name: "Score", Type: dictionaryType, Nullable: true},
+		{Name: "City", Type: dictionaryType, Nullable: true},
+		{Name: "When", Type: &arrow.TimestampType{Unit: arrow.Microsecond}, Nullable: true},
+		{Name: "Day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "Took", Type: arrow.FixedWidthTypes.Duration_us, Nullable: true},
+	}, nil)
+	for i, f := range want.Fields() {
+		if got := schema.Field(i); got.Name != f.Name || !arrow.TypeEqual(got.Type, f.Type) {
+			t.Errorf("got field %s %s, want %s %s", got.Name, got.Type, f.Name, f.Type)
+		}
+	}
+	score := schema.Field(0).Metadata
+	for key, want := range map[string]string{
+		"spss.label":        "Satisfaction score",
+		"spss.format":       "F8.2",
+		"spss.measure":      "Ordinal",
+		"spss.value_labels": `{"1":"Low","2":"High"}`,
+	} {
+		if i := score.FindKey(key); i < 0 || score.Values()[i] != want {
+			t.Errorf("got %s %v, want %q", key, score, want)
+		}
+	}
+	if md := schema.Metadata(); md.FindKey("spss.file_label") < 0 || md.Values()[md.FindKey("spss.file_label")] != "gospss arrow test" {
+		t.Errorf("got schema metadata %v", md)
+	}
+
+	// The values without a label are added to the dictionaries as they are
+	// read, after the labels.
+	rec, err := ar.Read()
+	if err != nil {
+		t.Fatalf("failed to read record ::: err >>> %s", err)
+	}
+	if rec.NumRows() != 3 {
+		t.Errorf("got %d rows, want 3", rec.NumRows())
+	}
+	for i, want := range [][]string{
+		{"Low", "2.5", "(null)"},
+		{"Stockholm", "Capital", "Malmö"},
+		{"2020-02-29 13:14:15Z", "(null)", "1920-02-29 13:14:15Z"},
+		{"2020-02-29", "(null)", "1920-02-29"},
+		{"5401500000us", "(null)", "90000000000us"},
+	} {
+		if got := texts(rec.Column(i)); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %s %q, want %q", rec.ColumnName(i), got, want)
+		}
+	}
+	if got, want := dictionaryOf(rec.Column(0)), []string{"Low", "High", "2.5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dictionary %q, want %q", got, want)
+	}
+	rec.Release()
+
+	rec, err = ar.Read()
+	if err != nil {
+		t.Fatalf("failed to read record ::: err >>> %s", err)
+	}
+	if got, want := texts(rec.Column(0)), []string{"High", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got scores %q, want %q", got, want)
+	}
+	if got, want := dictionaryOf(rec.Column(0)), []string{"Low", "High", "2.5", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dictionary %q, want %q", got, want)
+	}
+	if got, want := dictionaryOf(rec.Column(1)), []string{"Capital", "Stockholm", "Malmö", "Göteborg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dictionary %q, want %q", got, want)
+	}
+	rec.Release()
+	if _, err := ar.Read(); err != io.EOF {
+		t.Errorf("got error %v, want %v", err, io.EOF)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	d := &gospss.Dictionary{
+		Compression: gospss.ZLib,
+		Variables: []*gospss.Variable{
+			{Name: "Score", Numeric: true, Print: gospss.Format{Type: gospss.FormatF, Width: 8, Decimals: 2},
+				ValueLabels: []*gospss.ValueLabel{{Key: 1.0, Value: "Low"}, {Key: 2.0, Value: "High"}}},
+			{Name: "City", Width: 20},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := gospss.NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]gospss.Row{
+		{1.0, "Stockholm"},
+		{2.5, "Oslo"},
+		{math.NaN(), "Malmö"},
+		{2.0, "Göteborg"},
+		{3.0, "Oslo"},
+	}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	r, err := gospss.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	out := new(bytes.Buffer)
+	if err := WriteFile(out, r, WithBatchSize(3), WithDictionaries()); err != nil {
+		t.Fatalf("failed to write file ::: err >>> %s", err)
+	}
+
+	fr, err := ipc.NewFileReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read file ::: err >>> %s", err)
+	}
+	defer fr.Close()
+	if fr.NumDictionaries() != 1 || fr.NumRecords() != 2 {
+		t.Fatalf("got %d dictionaries and %d records, want 1 and 2", fr.NumDictionaries(), fr.NumRecords())
+	}
+	if typ := fr.Schema().Field(0).Type; !arrow.TypeEqual(typ, dictionaryType) {
+		t.Errorf("got type %s, want %s", typ, dictionaryType)
+	}
+	// The dictionary is complete from the first record on, as an IPC file
+	// can only hold one.
+	for i, want := range [][]string{
+		{"Low", "2.5", "(null)"},
+		{"High", "3"},
+	} {
+		rec, err := fr.Record(i)
+		if err != nil {
+			t.Fatalf("failed to read record ::: err >>> %s", err)
+		}
+		if got := texts(rec.Column(0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("got scores %q, want %q", got, want)
+		}
+		if got, want := dictionaryOf(rec.Column(0)), []string{"Low", "High", "2.5", "3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got dictionary %q, want %q", got, want)
+		}
+	}
+	rec, err := fr.Record(1)
+	if err != nil {
+		t.Fatalf("failed to read record ::: err >>> %s", err)
+	}
+	if got, want := texts(rec.Column(1)), []string{"Göteborg", "Oslo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got cities %q, want %q", got, want)
+	}
+
+	// Without a seeker the dictionaries can not be completed.
+	r, err = gospss.NewReader(io.MultiReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if err := WriteFile(new(bytes.Buffer), r, WithDictionaries()); err == nil {
+		t.Errorf("wrote dictionaries without seeking")
+	}
+}
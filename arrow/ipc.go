@@ -0,0 +1,91 @@
+package arrow
+
+import (
+	"errors"
+	"io"
+
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/hektorinho/gospss"
+)
+
+var ErrSeek = errors.New("Writer can only report its position.")
+
+// WriteFile writes the cases of r to w as an Arrow IPC file, converted as
+// described for NewReader.
+//
+// An Arrow IPC file holds a single dictionary per field, so with
+// WithDictionaries the cases are read twice, first to add the values
+// without a label to the dictionaries and then to write them, and r has
+// to be able to seek back, see gospss.Reader.SeekCase.
+func WriteFile(w io.Writer, r *gospss.Reader, opts ...Option) error {
+	ar := NewReader(r, opts...)
+	if err := ar.scan(); err != nil {
+		return err
+	}
+	fw, err := ipc.NewFileWriter(&offsetWriter{w: w}, ipc.WithSchema(ar.Schema()), ipc.WithAllocator(ar.opts.mem))
+	if err != nil {
+		return err
+	}
+	for {
+		rec, err := ar.Read()
+		if err == io.EOF {
+			return fw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		err = fw.Write(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scan reads the remaining cases to add the values without a label to the
+// dictionaries, and seeks back to where it began. It does nothing if no
+// field is dictionary encoded.
+func (r *Reader) scan() error {
+	var dicts bool
+	for _, d := range r.dicts {
+		dicts = dicts || d != nil
+	}
+	if !dicts {
+		return nil
+	}
+	start := r.r.CasesRead()
+	for {
+		b, err := r.r.ReadBatch(r.opts.batchSize)
+		if err == io.EOF {
+			return r.r.SeekCase(start)
+		}
+		if err != nil {
+			return err
+		}
+		for i, c := range b.Columns {
+			if d := r.dicts[i]; d != nil {
+				d.add(c, b.Len)
+			}
+		}
+	}
+}
+
+// offsetWriter counts the bytes written to w, so that it can tell the
+// writer of an IPC file where it is, which is all the seeking it does.
+type offsetWriter struct {
+	w   io.Writer
+	pos int64
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.Write(p)
+	ow.pos += int64(n)
+	return n, err
+}
+
+func (ow *offsetWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return ow.pos, ErrSeek
+	}
+	return ow.pos, nil
+}
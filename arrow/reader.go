@@ -0,0 +1,358 @@
+// Package arrow converts the cases of an IBM SPSS Statistics data file to
+// Apache Arrow records, with github.com/apache/arrow/go, and writes them as
+// an Arrow IPC file, the format read by DuckDB, Polars and pyarrow.
+package arrow
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/hektorinho/gospss"
+)
+
+// DefaultBatchSize is the number of cases in a record unless set with
+// WithBatchSize.
+const DefaultBatchSize = 64 * 1024
+
+// dictionaryType is the type of the fields of variables with value labels
+// with WithDictionaries.
+var dictionaryType = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+
+// An Option changes how a Reader converts the cases.
+type Option func(*options)
+
+// options holds the settings made by the options given to NewReader.
+type options struct {
+	// batchSize is the number of cases in a record.
+	batchSize int
+	// dictionaries makes labeled variables dictionary encoded.
+	dictionaries bool
+	// mem allocates the memory of the records.
+	mem memory.Allocator
+}
+
+// WithBatchSize sets the number of cases in a record, the last record of a
+// file can hold fewer.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithDictionaries makes the fields of variables with value labels
+// dictionary encoded, holding the labels of the values. Values without a
+// label are held as text, numbers formatted as by strconv.FormatFloat with
+// the 'f' format, and added to the dictionary when they are first read, so
+// the dictionary of a record begins with the dictionary of the records
+// before it.
+func WithDictionaries() Option {
+	return func(o *options) {
+		o.dictionaries = true
+	}
+}
+
+// WithAllocator sets the allocator of the memory of the records,
+// memory.DefaultAllocator unless set.
+func WithAllocator(mem memory.Allocator) Option {
+	return func(o *options) {
+		o.mem = mem
+	}
+}
+
+// Reader converts the cases of a gospss.Reader to records.
+type Reader struct {
+	r      *gospss.Reader
+	opts   *options
+	schema *arrow.Schema
+	// dicts holds the dictionary of each dictionary encoded field, and nil
+	// for the other fields.
+	dicts []*dictionary
+}
+
+// dictionary holds the strings the indices of a dictionary encoded field
+// refer to, indexed by the values of the variable.
+type dictionary struct {
+	values  []string
+	numbers map[float64]int32
+	strings map[string]int32
+}
+
+// NewReader returns a Reader of the variables selected on r, which has to
+// be done before, with a field for each variable.
+//
+// Numeric variables are of type float64, or date32, timestamp[us] without
+// a time zone or duration[us] if their print format is a date, a date and
+// time or a time format. String variables are of type utf8. Missing values
+// are null. The metadata of a field holds the variable label as
+// "spss.label", the print format as "spss.format", the measure as
+// "spss.measure" and the value labels as "spss.value_labels", a JSON
+// object from values to labels. The metadata of the schema holds the file
+// label as "spss.file_label" and the character encoding of the file as
+// "spss.encoding".
+func NewReader(r *gospss.Reader, opts ...Option) *Reader {
+	o := &options{batchSize: DefaultBatchSize, mem: memory.DefaultAllocator}
+	for _, opt := range opts {
+		opt(o)
+	}
+	ar := &Reader{r: r, opts: o}
+	var fields []arrow.Field
+	for _, v := range r.Selected() {
+		fields = append(fields, ar.field(v))
+	}
+	dict := r.Dictionary()
+	var m metadata
+	m.add("spss.file_label", dict.FileLabel)
+	m.add("spss.encoding", dict.Encoding)
+	md := arrow.NewMetadata(m.keys, m.values)
+	ar.schema = arrow.NewSchema(fields, &md)
+	return ar
+}
+
+// Schema returns the schema of the records.
+func (r *Reader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Read returns the next record, which has to be released by the caller. It
+// returns io.EOF when there are no more cases.
+func (r *Reader) Read() (arrow.Record, error) {
+	b, err := r.r.ReadBatch(r.opts.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]arrow.Array, len(b.Columns))
+	for i, c := range b.Columns {
+		columns[i] = r.array(i, c, b.Len)
+	}
+	rec := array.NewRecord(r.schema, columns, int64(b.Len))
+	for _, a := range columns {
+		a.Release()
+	}
+	return rec, nil
+}
+
+// metadata holds the key value pairs of the metadata of a field or the
+// schema, in order.
+type metadata struct {
+	keys, values []string
+}
+
+// add appends key and value, unless value is empty.
+func (m *metadata) add(key, value string) {
+	if value == "" {
+		return
+	}
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// field returns the field of v, and indexes its dictionary if it has one.
+func (r *Reader) field(v *gospss.Variable) arrow.Field {
+	f := arrow.Field{Name: v.Name, Type: fieldType(v), Nullable: true}
+	var d *dictionary
+	if r.opts.dictionaries && len(v.ValueLabels) > 0 {
+		f.Type = dictionaryType
+		d = &dictionary{numbers: make(map[float64]int32), strings: make(map[string]int32)}
+		labels := make(map[string]int32)
+		for _, vl := range v.ValueLabels {
+			index, ok := labels[vl.Value]
+			if !ok {
+				index = int32(len(d.values))
+				d.values = append(d.values, vl.Value)
+				labels[vl.Value] = index
+			}
+			switch key := vl.Key.(type) {
+			case float64:
+				d.numbers[key] = index
+			case string:
+				d.strings[strings.TrimRight(key, " ")] = index
+			}
+		}
+	}
+	r.dicts = append(r.dicts, d)
+
+	var m metadata
+	m.add("spss.label", v.Label)
+	m.add("spss.format", v.Print.String())
+	if v.Measure != gospss.MeasureUnknown {
+		m.add("spss.measure", v.Measure.String())
+	}
+	m.add("spss.value_labels", valueLabels(v))
+	f.Metadata = arrow.NewMetadata(m.keys, m.values)
+	return f
+}
+
+// fieldType returns the type of the values of v.
+func fieldType(v *gospss.Variable) arrow.DataType {
+	if !v.Numeric {
+		return arrow.BinaryTypes.String
+	}
+	switch v.Print.Type {
+	case gospss.FormatDATE, gospss.FormatADATE, gospss.FormatEDATE, gospss.FormatSDATE, gospss.FormatJDATE,
+		gospss.FormatMOYR, gospss.FormatQYR, gospss.FormatWKYR:
+		return arrow.FixedWidthTypes.Date32
+	case gospss.FormatDATETIME, gospss.FormatYMDHMS:
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	case gospss.FormatTIME, gospss.FormatDTIME, gospss.FormatMTIME:
+		return arrow.FixedWidthTypes.Duration_us
+	default:
+		return arrow.PrimitiveTypes.Float64
+	}
+}
+
+// valueLabels returns the value labels of v as a JSON object, in order, or
+// "" if it has none.
+func valueLabels(v *gospss.Variable) string {
+	if len(v.ValueLabels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for _, vl := range v.ValueLabels {
+		var key string
+		switch k := vl.Key.(type) {
+		case float64:
+			key = strconv.FormatFloat(k, 'f', -1, 64)
+		case string:
+			key = strings.TrimRight(k, " ")
+		default:
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		// Marshalling a string can not fail.
+		k, _ := json.Marshal(key)
+		l, _ := json.Marshal(vl.Value)
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(l)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// index returns the index in d of the j'th value of c, adding the value to
+// d if it has no label.
+func (d *dictionary) index(c *gospss.Column, j int) int32 {
+	if c.Variable.Numeric {
+		f := c.Floats[j]
+		index, ok := d.numbers[f]
+		if !ok {
+			index = int32(len(d.values))
+			d.values = append(d.values, strconv.FormatFloat(f, 'f', -1, 64))
+			d.numbers[f] = index
+		}
+		return index
+	}
+	s := c.Strings[j]
+	index, ok := d.strings[s]
+	if !ok {
+		index = int32(len(d.values))
+		d.values = append(d.values, s)
+		d.strings[s] = index
+	}
+	return index
+}
+
+// add adds the values without a label of the n cases of c to d.
+func (d *dictionary) add(c *gospss.Column, n int) {
+	for j := 0; j < n; j++ {
+		if c.IsValid(j) {
+			d.index(c, j)
+		}
+	}
+}
+
+// array returns the values of the column c of the i'th field, of n cases.
+func (r *Reader) array(i int, c *gospss.Column, n int) arrow.Array {
+	mem := r.opts.mem
+	if d := r.dicts[i]; d != nil {
+		indices := array.NewInt32Builder(mem)
+		defer indices.Release()
+		for j := 0; j < n; j++ {
+			if c.IsValid(j) {
+				indices.Append(d.index(c, j))
+			} else {
+				indices.AppendNull()
+			}
+		}
+		values := array.NewStringBuilder(mem)
+		defer values.Release()
+		values.AppendValues(d.values, nil)
+		ia, va := indices.NewArray(), values.NewArray()
+		defer ia.Release()
+		defer va.Release()
+		return array.NewDictionaryArray(dictionaryType, ia, va)
+	}
+
+	switch typ := r.schema.Field(i).Type.(type) {
+	case *arrow.StringType:
+		b := array.NewStringBuilder(mem)
+		defer b.Release()
+		b.AppendValues(c.Strings[:n], valid(c, n))
+		return b.NewArray()
+	case *arrow.TimestampType:
+		b := array.NewTimestampBuilder(mem, typ)
+		defer b.Release()
+		for j := 0; j < n; j++ {
+			if c.IsValid(j) {
+				b.Append(arrow.Timestamp(gospss.TimeFromSPSS(c.Floats[j]).UnixMicro()))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray()
+	case *arrow.Date32Type:
+		b := array.NewDate32Builder(mem)
+		defer b.Release()
+		for j := 0; j < n; j++ {
+			if c.IsValid(j) {
+				b.Append(arrow.Date32(days(gospss.TimeFromSPSS(c.Floats[j]))))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray()
+	case *arrow.DurationType:
+		b := array.NewDurationBuilder(mem, typ)
+		defer b.Release()
+		for j := 0; j < n; j++ {
+			if c.IsValid(j) {
+				b.Append(arrow.Duration(gospss.DurationFromSPSS(c.Floats[j]) / time.Microsecond))
+			} else {
+				b.AppendNull()
+			}
+		}
+		return b.NewArray()
+	default:
+		b := array.NewFloat64Builder(mem)
+		defer b.Release()
+		b.AppendValues(c.Floats[:n], valid(c, n))
+		return b.NewArray()
+	}
+}
+
+// valid returns whether each of the n values of c is not missing.
+func valid(c *gospss.Column, n int) []bool {
+	v := make([]bool, n)
+	for j := range v {
+		v[j] = c.IsValid(j)
+	}
+	return v
+}
+
+// days returns the number of days from 1 January 1970 to t.
+func days(t time.Time) int32 {
+	s := t.Unix()
+	d := s / 86400
+	if s%86400 < 0 {
+		d--
+	}
+	return int32(d)
+}
@@ -0,0 +1,107 @@
+package gospss
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// roleAttribute is the variable attribute that holds the role of a variable.
+const roleAttribute = "$@Role"
+
+// parseAttributes parses the attributes of a data file attributes record or
+// of one variable in a variable attributes record. Each attribute is a name
+// followed by its values in parentheses, each value quoted and followed by a
+// new line, as in:
+//
+//	Question('How old are you?'\n)Routing('Q1'\n'Q2'\n)
+//
+// It returns the attributes and what follows them, either "" or the "/"
+// that separates the variables of a variable attributes record. Parsing
+// stops at the first malformed attribute.
+func parseAttributes(s string) (map[string][]string, string) {
+	attributes := make(map[string][]string)
+	for s != "" && s[0] != '/' {
+		open := strings.IndexByte(s, '(')
+		if open < 0 {
+			return attributes, ""
+		}
+		name := strings.TrimSpace(s[:open])
+		s = s[open+1:]
+		values := []string{}
+		for {
+			if s == "" {
+				attributes[name] = values
+				return attributes, ""
+			}
+			if s[0] == ')' {
+				s = s[1:]
+				break
+			}
+			end := strings.IndexByte(s, '\n')
+			if end < 0 {
+				end = len(s)
+			}
+			value := s[:end]
+			value = strings.TrimPrefix(value, "'")
+			value = strings.TrimSuffix(value, "'")
+			values = append(values, value)
+			if end < len(s) {
+				end++
+			}
+			s = s[end:]
+		}
+		attributes[name] = values
+	}
+	return attributes, s
+}
+
+// parseVariableAttributes parses a variable attributes record, in which
+// the attributes of each variable follow its name and a ":", and variables
+// are separated by "/", and returns the attributes by variable name.
+func parseVariableAttributes(s string) map[string]map[string][]string {
+	variables := make(map[string]map[string][]string)
+	for s != "" {
+		colon := strings.IndexByte(s, ':')
+		if colon < 0 {
+			break
+		}
+		name := strings.TrimSpace(s[:colon])
+		var attributes map[string][]string
+		attributes, s = parseAttributes(s[colon+1:])
+		variables[strings.ToLower(name)] = attributes
+		s = strings.TrimPrefix(s, "/")
+	}
+	return variables
+}
+
+// formatAttributes formats attributes as parsed by parseAttributes, sorted
+// by name.
+func formatAttributes(attributes map[string][]string) string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('(')
+		for _, value := range attributes[name] {
+			b.WriteString("'" + strings.ReplaceAll(value, "\n", " ") + "'\n")
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// variableAttributes returns the attributes of v as written to the variable
+// attributes record, which includes the role.
+func variableAttributes(v *Variable) map[string][]string {
+	attributes := make(map[string][]string, len(v.Attributes)+1)
+	for name, values := range v.Attributes {
+		attributes[name] = values
+	}
+	attributes[roleAttribute] = []string{strconv.Itoa(int(v.Role))}
+	return attributes
+}
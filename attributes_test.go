@@ -0,0 +1,58 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestParseAttributes(t *testing.T) {
+	attributes, rest := parseAttributes("Question('Age/years: (rounded)'\n)Routing('Q1'\n'Q2'\n'it''s'\n)Empty()/Next:")
+	want := "map[Empty:[] Question:[Age/years: (rounded)] Routing:[Q1 Q2 it''s]]"
+	if fmt.Sprint(attributes) != want || rest != "/Next:" {
+		t.Errorf("got %v and %q, want %s and \"/Next:\"", attributes, rest, want)
+	}
+
+	variables := parseVariableAttributes("Age:$@Role('1'\n)Question('How old?'\n)/Name:$@Role('0'\n)/Broken:Question('no end")
+	want = "map[age:map[$@Role:[1] Question:[How old?]] broken:map[Question:[no end]] name:map[$@Role:[0]]]"
+	if fmt.Sprint(variables) != want {
+		t.Errorf("got %v, want %s", variables, want)
+	}
+}
+
+func TestAttributesRoundTrip(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.Attributes = map[string][]string{
+		"Project": {"Panel/2016: wave (3)"},
+		"Waves":   {"1", "2", "3"},
+	}
+	d.Variables[0].Role = RoleSplit
+	d.Variables[1].Role = RoleTarget
+	d.Variables[1].Attributes = map[string][]string{
+		"Question": {"How satisfied are you?"},
+		"Routing":  {"Q1", "Q2"},
+	}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer ::: err >>> %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	got := r.Dictionary()
+	if fmt.Sprint(got.Attributes) != fmt.Sprint(d.Attributes) {
+		t.Errorf("got file attributes %v, want %v", got.Attributes, d.Attributes)
+	}
+	for i, v := range got.Variables {
+		if v.Role != d.Variables[i].Role || fmt.Sprint(v.Attributes) != fmt.Sprint(d.Variables[i].Attributes) {
+			t.Errorf("variable %s has role %s and attributes %v, want %s and %v",
+				v.Name, v.Role, v.Attributes, d.Variables[i].Role, d.Variables[i].Attributes)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package gospss
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+var ErrBatchSize = errors.New("Batch size must be positive.")
+
+// Batch holds a number of cases column by column, as read by ReadBatch.
+type Batch struct {
+	// Len is the number of cases in the batch.
+	Len int
+
+	// Columns holds the values of each selected variable, in the order of
+	// the values in a row.
+	Columns []*Column
+}
+
+// Column holds the values of one variable in a Batch.
+type Column struct {
+	// Variable is the variable of the values.
+	Variable *Variable
+
+	// Floats holds the values of a numeric variable, with NaN for the
+	// system-missing value, and Strings the values of a string variable,
+	// without padding. Only the one for the type of the variable is set.
+	Floats  []float64
+	Strings []string
+
+	// Valid is a bitmap with a bit for every case, in least significant bit
+	// order, that is set unless the value is system- or user-missing.
+	Valid []byte
+}
+
+// IsValid reports whether the i'th value of c is not missing.
+func (c *Column) IsValid(i int) bool {
+	return c.Valid[i/8]&(1<<(i%8)) != 0
+}
+
+// NullCount returns the number of missing values in c.
+func (c *Column) NullCount() int {
+	var nulls int
+	for i := 0; i < c.len(); i++ {
+		if !c.IsValid(i) {
+			nulls++
+		}
+	}
+	return nulls
+}
+
+// len returns the number of values in c.
+func (c *Column) len() int {
+	if c.Variable.Numeric {
+		return len(c.Floats)
+	}
+	return len(c.Strings)
+}
+
+// truncate drops the values after the first n, such as those of a case
+// that ended early.
+func (c *Column) truncate(n int) {
+	if c.Variable.Numeric {
+		c.Floats = c.Floats[:n]
+	} else {
+		c.Strings = c.Strings[:n]
+	}
+	c.Valid = c.Valid[:(n+7)/8]
+	if n%8 != 0 {
+		c.Valid[n/8] &= 1<<(n%8) - 1
+	}
+}
+
+// Column returns the column of the variable with the long or short name
+// name, matched case insensitively, or nil if the variable is not in b.
+func (b *Batch) Column(name string) *Column {
+	for _, c := range b.Columns {
+		if strings.EqualFold(c.Variable.Name, name) || strings.EqualFold(c.Variable.id, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// ReadBatch reads up to n cases in to a Batch, with a column for each of
+// the variables selected with Select. The last batch of a file can hold
+// fewer cases, and when there are no more cases it returns io.EOF.
+//
+// The values are stored as they are in the file, so the options of the
+// Reader do not apply, and missing values are marked in the validity
+// bitmap of the column instead.
+func (r *Reader) ReadBatch(n int) (*Batch, error) {
+	if n < 1 {
+		return nil, ErrBatchSize
+	}
+	b := &Batch{}
+	for _, v := range r.Selected() {
+		c := &Column{Variable: v, Valid: make([]byte, 0, (n+7)/8)}
+		if v.Numeric {
+			c.Floats = make([]float64, 0, n)
+		} else {
+			c.Strings = make([]string, 0, n)
+		}
+		b.Columns = append(b.Columns, c)
+	}
+	sink := batchSink{b}
+	for b.Len < n {
+		if b.Len%8 == 0 {
+			for _, c := range b.Columns {
+				c.Valid = append(c.Valid, 0)
+			}
+		}
+		err := r.scanCase(sink)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.reportProgress()
+		b.Len++
+	}
+	if b.Len == 0 {
+		return nil, io.EOF
+	}
+	for _, c := range b.Columns {
+		if c.len() > b.Len {
+			c.truncate(b.Len)
+		}
+		c.Valid = c.Valid[:(b.Len+7)/8]
+	}
+	return b, nil
+}
+
+// batchSink appends the values of a case to the columns of a Batch.
+type batchSink struct {
+	b *Batch
+}
+
+func (s batchSink) setNumber(col int, f float64) {
+	c := s.b.Columns[col]
+	c.Floats = append(c.Floats, f)
+	s.setValid(c, !math.IsNaN(f) && !c.Variable.Missing.isMissingNumber(f))
+}
+
+func (s batchSink) setString(col int, str string) {
+	c := s.b.Columns[col]
+	c.Strings = append(c.Strings, str)
+	s.setValid(c, !c.Variable.Missing.isMissingString(str))
+}
+
+// setValid sets the bit of the current case in the validity bitmap of c.
+func (s batchSink) setValid(c *Column, valid bool) {
+	if valid {
+		c.Valid[s.b.Len/8] |= 1 << (s.b.Len % 8)
+	}
+}
@@ -0,0 +1,104 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestReadBatch(t *testing.T) {
+	d := testDictionary(ZLib)
+	d.Variables[0].Missing = MissingSpec{Values: []float64{-99}}
+	d.Variables[3].Missing = MissingSpec{Strings: []string{"BCDE"}}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll(testRows); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), WithValueLabels())
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if _, err := r.ReadBatch(0); err != ErrBatchSize {
+		t.Errorf("got error %v, want %v", err, ErrBatchSize)
+	}
+	b, err := r.ReadBatch(3)
+	if err != nil {
+		t.Fatalf("failed to read batch ::: err >>> %s", err)
+	}
+	if b.Len != 3 || len(b.Columns) != 4 {
+		t.Fatalf("got %d cases and %d columns", b.Len, len(b.Columns))
+	}
+	score := b.Column("score")
+	if score.Floats[0] != 1 || score.Floats[1] != 2.5 || !math.IsNaN(score.Floats[2]) || score.Valid[0] != 0b011 || score.NullCount() != 1 {
+		t.Errorf("got score %v, valid %b", score.Floats, score.Valid)
+	}
+	if got, want := fmt.Sprintf("%q %b", b.Columns[3].Strings, b.Columns[3].Valid), `["A" "" "BCDE"] [11]`; got != want {
+		t.Errorf("got code %s, want %s", got, want)
+	}
+
+	b, err = r.ReadBatch(3)
+	if err != nil {
+		t.Fatalf("failed to read batch ::: err >>> %s", err)
+	}
+	id := b.Column("RespondentID")
+	if b.Len != 1 || len(id.Floats) != 1 || id.Floats[0] != -99 || id.IsValid(0) || len(id.Valid) != 1 {
+		t.Errorf("got %d cases, id %v, valid %b", b.Len, id.Floats, id.Valid)
+	}
+	if _, err := r.ReadBatch(3); err != io.EOF {
+		t.Errorf("got error %v, want %v", err, io.EOF)
+	}
+}
+
+func TestReadBatchFile(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if err := r.SeekCase(0); err != nil {
+		t.Fatalf("failed to seek ::: err >>> %s", err)
+	}
+
+	var n int
+	for {
+		b, err := r.ReadBatch(5)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read batch ::: err >>> %s", err)
+		}
+		for i := 0; i < b.Len; i, n = i+1, n+1 {
+			for col, c := range b.Columns {
+				var got interface{}
+				if c.Variable.Numeric {
+					got = c.Floats[i]
+				} else {
+					got = c.Strings[i]
+				}
+				if fmt.Sprint(got) != fmt.Sprint(rows[n][col]) {
+					t.Fatalf("case %d, variable %s: got %v, want %v", n, c.Variable.Name, got, rows[n][col])
+				}
+			}
+		}
+	}
+	if n != len(rows) {
+		t.Errorf("got %d cases, want %d", n, len(rows))
+	}
+}
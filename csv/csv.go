@@ -0,0 +1,202 @@
+// Package csv exports the cases of a gospss.Reader as comma or tab
+// separated values, with encoding/csv.
+package csv
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/hektorinho/gospss"
+)
+
+// batchSize is the number of cases read at a time.
+const batchSize = 1024
+
+// Header is the header row written before the cases.
+type Header int
+
+const (
+	// LongNames writes a header row of the variable names.
+	LongNames Header = iota
+	// ShortNames writes a header row of the names from the variable
+	// records, of at most 8 bytes.
+	ShortNames
+	// NoHeader does not write a header row.
+	NoHeader
+)
+
+// An Option changes how WriteFile writes the cases.
+type Option func(*options)
+
+// options holds the settings made by the options given to WriteFile.
+type options struct {
+	// comma is the field delimiter.
+	comma rune
+	// crlf ends the lines with \r\n instead of \n.
+	crlf bool
+	// header is the header row.
+	header Header
+	// labels writes the labels of values instead of the values.
+	labels bool
+	// sysmis is written for the system-missing value.
+	sysmis string
+	// userMissing, if set, is written for user-missing values instead of
+	// the values.
+	userMissing    string
+	hasUserMissing bool
+	// dates writes values of date and time formats as in the print format.
+	dates bool
+	// decimals writes numbers with the decimals of the variable.
+	decimals bool
+}
+
+// WithComma sets the field delimiter, a comma unless set. Use '\t' to
+// write tab separated values.
+func WithComma(r rune) Option {
+	return func(o *options) {
+		o.comma = r
+	}
+}
+
+// WithCRLF ends the lines with \r\n instead of \n.
+func WithCRLF() Option {
+	return func(o *options) {
+		o.crlf = true
+	}
+}
+
+// WithHeader sets the header row, LongNames unless set.
+func WithHeader(h Header) Option {
+	return func(o *options) {
+		o.header = h
+	}
+}
+
+// WithValueLabels writes the label of a value instead of the value if the
+// variable has a label for it.
+func WithValueLabels() Option {
+	return func(o *options) {
+		o.labels = true
+	}
+}
+
+// WithSystemMissing sets the text written for the system-missing value, an
+// empty field unless set.
+func WithSystemMissing(s string) Option {
+	return func(o *options) {
+		o.sysmis = s
+	}
+}
+
+// WithUserMissing sets the text written for user-missing values, which are
+// otherwise written like any other value.
+func WithUserMissing(s string) Option {
+	return func(o *options) {
+		o.userMissing = s
+		o.hasUserMissing = true
+	}
+}
+
+// WithDates writes values of variables with a date or time print format as
+// the format shows them, such as 29-FEB-2020 for DATE11, instead of the
+// number of seconds.
+func WithDates() Option {
+	return func(o *options) {
+		o.dates = true
+	}
+}
+
+// WithDecimals writes numbers with the number of decimals of the variable,
+// Variable.Decimal, instead of as many as needed.
+func WithDecimals() Option {
+	return func(o *options) {
+		o.decimals = true
+	}
+}
+
+// WriteFile writes the cases left to read from r to w, a row for each case
+// with a field for each of the variables selected on r.
+func WriteFile(w io.Writer, r *gospss.Reader, opts ...Option) error {
+	o := &options{comma: ','}
+	for _, opt := range opts {
+		opt(o)
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = o.comma
+	cw.UseCRLF = o.crlf
+
+	variables := r.Selected()
+	record := make([]string, len(variables))
+	if o.header != NoHeader {
+		for i, v := range variables {
+			if o.header == ShortNames {
+				record[i] = v.ShortName()
+			} else {
+				record[i] = v.Name
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	for {
+		b, err := r.ReadBatch(batchSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for i := 0; i < b.Len; i++ {
+			for j, c := range b.Columns {
+				record[j] = o.value(c, i)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// value returns the text of the i'th value of c.
+func (o *options) value(c *gospss.Column, i int) string {
+	v := c.Variable
+	if v.Numeric {
+		f := c.Floats[i]
+		switch {
+		case math.IsNaN(f):
+			return o.sysmis
+		case o.hasUserMissing && !c.IsValid(i):
+			return o.userMissing
+		}
+		if o.labels {
+			if label, ok := v.LabelFor(f); ok {
+				return label
+			}
+		}
+		if o.dates {
+			if s, ok := formatDate(v.Print, f); ok {
+				return s
+			}
+		}
+		if o.decimals {
+			return strconv.FormatFloat(f, 'f', v.Decimal, 64)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	s := c.Strings[i]
+	if o.hasUserMissing && !c.IsValid(i) {
+		return o.userMissing
+	}
+	if o.labels {
+		if label, ok := v.LabelFor(s); ok {
+			return label
+		}
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package csv
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hektorinho/gospss"
+)
+
+func TestWriteFile(t *testing.T) {
+	when := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	d := &gospss.Dictionary{
+		Compression: gospss.Bytecode,
+		Variables: []*gospss.Variable{
+			{Name: "RespondentID", Numeric: true, Print: gospss.Format{Type: gospss.FormatF, Width: 8},
+				Missing: gospss.MissingSpec{Values: []float64{-99}}},
+			{Name: "Score", Numeric: true, Decimal: 2, Print: gospss.Format{Type: gospss.FormatF, Width: 8, Decimals: 2},
+				ValueLabels: []*gospss.ValueLabel{{Key: 1.0, Value: "Low"}, {Key: 2.5, Value: "High, very"}}},
+			{Name: "Weight", Numeric: true, Decimal: 2, Print: gospss.Format{Type: gospss.FormatF, Width: 8, Decimals: 2}},
+			{Name: "City", Width: 20, Missing: gospss.MissingSpec{Strings: []string{"NA"}}},
+			{Name: "Day", Numeric: true, Print: gospss.Format{Type: gospss.FormatDATE, Width: 11}},
+			{Name: "Took", Numeric: true, Print: gospss.Format{Type: gospss.FormatTIME, Width: 11, Decimals: 2}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := gospss.NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]gospss.Row{
+		{1.0, 1.0, 3.0, "Stockholm", when, 90*time.Minute + 1500*time.Millisecond},
+		{-99.0, 2.5, 0.126, "NA", nil, nil},
+		{3.0, math.NaN(), 1.5, "Oslo", when.AddDate(-100, 0, 0), 25 * time.Hour},
+	}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	file := buf.Bytes()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, "RespondentID,Score,Weight,City,Day,Took\n" +
+			"1,1,3,Stockholm,13802361255,5401.5\n" +
+			"-99,2.5,0.126,NA,,\n" +
+			"3,,1.5,Oslo,10646601255,90000\n"},
+		{"formatted", []Option{WithHeader(ShortNames), WithDates(), WithDecimals(), WithValueLabels()}, "RESPONDE,SCORE,WEIGHT,CITY,DAY,TOOK\n" +
+			"1,Low,3.00,Stockholm,29-FEB-2020,1:30:01.50\n" +
+			"-99,\"High, very\",0.13,NA,,\n" +
+			"3,,1.50,Oslo,29-FEB-1920,25:00:00.00\n"},
+		{"missing", []Option{WithHeader(NoHeader), WithComma('\t'), WithCRLF(), WithSystemMissing("."), WithUserMissing("NA*")}, "" +
+			"1\t1\t3\tStockholm\t13802361255\t5401.5\r\n" +
+			"NA*\t2.5\t0.126\tNA*\t.\t.\r\n" +
+			"3\t.\t1.5\tOslo\t10646601255\t90000\r\n"},
+	}
+	for _, tt := range tests {
+		r, err := gospss.NewReader(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("failed to read ::: err >>> %s", err)
+		}
+		out := new(bytes.Buffer)
+		if err := WriteFile(out, r, tt.opts...); err != nil {
+			t.Fatalf("failed to write %s file ::: err >>> %s", tt.name, err)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("got %s file\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	value := gospss.TimeToSPSS(time.Date(2021, time.March, 4, 5, 6, 7, 250e6, time.UTC))
+	tests := []struct {
+		format gospss.Format
+		value  float64
+		want   string
+	}{
+		{gospss.Format{Type: gospss.FormatDATE, Width: 11}, value, "04-MAR-2021"},
+		{gospss.Format{Type: gospss.FormatDATE, Width: 9}, value, "04-MAR-21"},
+		{gospss.Format{Type: gospss.FormatADATE, Width: 10}, value, "03/04/2021"},
+		{gospss.Format{Type: gospss.FormatEDATE, Width: 8}, value, "04.03.21"},
+		{gospss.Format{Type: gospss.FormatSDATE, Width: 10}, value, "2021/03/04"},
+		{gospss.Format{Type: gospss.FormatJDATE, Width: 7}, value, "2021063"},
+		{gospss.Format{Type: gospss.FormatMOYR, Width: 8}, value, "MAR 2021"},
+		{gospss.Format{Type: gospss.FormatQYR, Width: 6}, value, "1 Q 21"},
+		{gospss.Format{Type: gospss.FormatWKYR, Width: 10}, value, "09 WK 2021"},
+		{gospss.Format{Type: gospss.FormatDATETIME, Width: 17}, value, "04-MAR-2021 05:06"},
+		{gospss.Format{Type: gospss.FormatDATETIME, Width: 23, Decimals: 2}, value, "04-MAR-2021 05:06:07.25"},
+		{gospss.Format{Type: gospss.FormatYMDHMS, Width: 19}, value, "2021-03-04 05:06:07"},
+		{gospss.Format{Type: gospss.FormatTIME, Width: 5}, 3725, "1:02"},
+		{gospss.Format{Type: gospss.FormatTIME, Width: 8}, -3725.6, "-1:02:06"},
+		{gospss.Format{Type: gospss.FormatDTIME, Width: 11}, 90061, "1 01:01:01"},
+		{gospss.Format{Type: gospss.FormatMTIME, Width: 8, Decimals: 1}, 3725.25, "62:05.3"},
+		{gospss.Format{Type: gospss.FormatWKDAY, Width: 3}, 2, "MON"},
+		{gospss.Format{Type: gospss.FormatMONTH, Width: 9}, 3, "MARCH"},
+	}
+	for _, tt := range tests {
+		got, ok := formatDate(tt.format, tt.value)
+		if !ok || got != tt.want {
+			t.Errorf("got %s %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		format gospss.Format
+		value  float64
+	}{
+		{gospss.Format{Type: gospss.FormatF, Width: 8}, 1},
+		{gospss.Format{Type: gospss.FormatMONTH, Width: 3}, 13},
+	} {
+		if got, ok := formatDate(tt.format, tt.value); ok {
+			t.Errorf("got %s %q, want no date", tt.format, got)
+		}
+	}
+}
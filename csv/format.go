@@ -0,0 +1,124 @@
+package csv
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hektorinho/gospss"
+)
+
+// formatDate returns value as a variable with the date or time print format
+// f shows it. It reports false if f is not a date or time format, or if
+// value is not a day of the week or a month for WKDAY or MONTH.
+//
+// Formats narrower than the widest form of their type show the year with 2
+// digits, or leave out the seconds of a time.
+func formatDate(f gospss.Format, value float64) (string, bool) {
+	p := math.Pow(10, float64(f.Decimals))
+	value = math.Round(value*p) / p
+	whole := math.Floor(value)
+	t := gospss.TimeFromSPSS(whole)
+	// Months are written in upper case, as in JAN.
+	layout := func(long, short string, width int) string {
+		if f.Width < width {
+			return strings.ToUpper(t.Format(short))
+		}
+		return strings.ToUpper(t.Format(long))
+	}
+	clock := func(layout string, width int) string {
+		s := strings.ToUpper(t.Format(layout + " 15:04"))
+		if f.Width < width {
+			return s
+		}
+		return s + ":" + seconds(t.Second(), value-whole, f.Decimals)
+	}
+	switch f.Type {
+	case gospss.FormatDATE:
+		return layout("02-Jan-2006", "02-Jan-06", 11), true
+	case gospss.FormatADATE:
+		return layout("01/02/2006", "01/02/06", 10), true
+	case gospss.FormatEDATE:
+		return layout("02.01.2006", "02.01.06", 10), true
+	case gospss.FormatSDATE:
+		return layout("2006/01/02", "06/01/02", 10), true
+	case gospss.FormatJDATE:
+		return layout("2006002", "06002", 7), true
+	case gospss.FormatMOYR:
+		return layout("Jan 2006", "Jan 06", 8), true
+	case gospss.FormatQYR:
+		quarter := strconv.Itoa((int(t.Month())+2)/3) + " Q "
+		return quarter + layout("2006", "06", 8), true
+	case gospss.FormatWKYR:
+		week := fmt.Sprintf("%02d WK ", (t.YearDay()-1)/7+1)
+		return week + layout("2006", "06", 10), true
+	case gospss.FormatDATETIME:
+		return clock("02-Jan-2006", 20), true
+	case gospss.FormatYMDHMS:
+		return clock("2006-01-02", 19), true
+	case gospss.FormatTIME:
+		return duration(value, f, 8, func(s int64) string {
+			return fmt.Sprintf("%d:%02d", s/3600, s/60%60)
+		}), true
+	case gospss.FormatDTIME:
+		return duration(value, f, 11, func(s int64) string {
+			return fmt.Sprintf("%d %02d:%02d", s/86400, s/3600%24, s/60%60)
+		}), true
+	case gospss.FormatMTIME:
+		return duration(value, f, 0, func(s int64) string {
+			return strconv.FormatInt(s/60, 10)
+		}), true
+	case gospss.FormatWKDAY:
+		if value < 1 || value > 7 {
+			return "", false
+		}
+		// IBM SPSS Statistics counts days from 1 for Sunday.
+		return name(time.Weekday(int(value)-1).String(), f.Width), true
+	case gospss.FormatMONTH:
+		if value < 1 || value > 12 {
+			return "", false
+		}
+		return name(time.Month(int(value)).String(), f.Width), true
+	default:
+		return "", false
+	}
+}
+
+// duration returns the seconds value of a time format f, as written by
+// layout from the whole seconds, followed by the seconds of the minute
+// unless f is narrower than width.
+func duration(value float64, f gospss.Format, width int, layout func(int64) string) string {
+	var sign string
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	whole := math.Floor(value)
+	s := int64(whole)
+	text := sign + layout(s)
+	if f.Width < width {
+		return text
+	}
+	return text + ":" + seconds(int(s%60), value-whole, f.Decimals)
+}
+
+// seconds returns the seconds sec with the fraction frac to the given
+// number of decimals, as in 05 or 05.25.
+func seconds(sec int, frac float64, decimals int) string {
+	s := fmt.Sprintf("%02d", sec)
+	if decimals > 0 {
+		s += strconv.FormatFloat(frac, 'f', decimals, 64)[1:]
+	}
+	return s
+}
+
+// name returns the name of a day or month in upper case, shortened to the
+// width of the format.
+func name(s string, width int) string {
+	s = strings.ToUpper(s)
+	if width > 0 && width < len(s) {
+		return s[:width]
+	}
+	return s
+}
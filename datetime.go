@@ -0,0 +1,85 @@
+package gospss
+
+import (
+	"math"
+	"time"
+)
+
+// spssEpoch is the start of the Gregorian calendar, from which IBM SPSS
+// Statistics counts dates in seconds.
+var spssEpoch = time.Date(1582, time.October, 14, 0, 0, 0, 0, time.UTC)
+
+// TimeFromSPSS returns the time of a value of a variable with a date
+// format, such as DATE or DATETIME, which counts seconds since 14 October
+// 1582. The time is in UTC as the file does not have a time zone.
+func TimeFromSPSS(seconds float64) time.Time {
+	// The range of a time.Duration is only about 292 years, so the time is
+	// counted in Unix seconds instead.
+	whole := math.Floor(seconds)
+	micro := math.Round((seconds - whole) * 1e6)
+	return time.Unix(spssEpoch.Unix()+int64(whole), int64(micro)*1e3).UTC()
+}
+
+// TimeToSPSS returns the value of t for a variable with a date format.
+func TimeToSPSS(t time.Time) float64 {
+	return float64(t.Unix()-spssEpoch.Unix()) + float64(t.Nanosecond())/1e9
+}
+
+// DurationFromSPSS returns the duration of a value of a variable with a
+// time format, such as TIME or DTIME, which counts seconds.
+func DurationFromSPSS(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1e6)) * time.Microsecond
+}
+
+// DurationToSPSS returns the value of d for a variable with a time format.
+func DurationToSPSS(d time.Duration) float64 {
+	return d.Seconds()
+}
+
+// WithDates makes a Reader return values of variables with a date or time
+// print format as Go types instead of float64:
+//
+//   - time.Time for DATE, ADATE, EDATE, SDATE, JDATE, DATETIME, YMDHMS,
+//     MOYR, QYR and WKYR
+//   - time.Duration for TIME, DTIME and MTIME
+//   - time.Weekday for WKDAY
+//   - time.Month for MONTH
+//
+// Missing values are returned as nil.
+func WithDates() Option {
+	return func(o *options) {
+		o.dates = true
+	}
+}
+
+// dateValue converts a value of a variable with the format type t to the
+// Go type described by WithDates. It reports false if t is not a date or
+// time format.
+func dateValue(t FormatType, f float64) (interface{}, bool) {
+	switch t {
+	case FormatDATE, FormatADATE, FormatEDATE, FormatSDATE, FormatJDATE, FormatDATETIME,
+		FormatYMDHMS, FormatMOYR, FormatQYR, FormatWKYR:
+		if math.IsNaN(f) {
+			return nil, true
+		}
+		return TimeFromSPSS(f), true
+	case FormatTIME, FormatDTIME, FormatMTIME:
+		if math.IsNaN(f) {
+			return nil, true
+		}
+		return DurationFromSPSS(f), true
+	case FormatWKDAY:
+		if math.IsNaN(f) {
+			return nil, true
+		}
+		// IBM SPSS Statistics counts days from 1 for Sunday.
+		return time.Weekday(int(f) - 1), true
+	case FormatMONTH:
+		if math.IsNaN(f) {
+			return nil, true
+		}
+		return time.Month(int(f)), true
+	default:
+		return nil, false
+	}
+}
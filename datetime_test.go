@@ -0,0 +1,90 @@
+package gospss
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTimeConversion(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    time.Time
+	}{
+		{0, time.Date(1582, time.October, 14, 0, 0, 0, 0, time.UTC)},
+		{13608086400, time.Date(2014, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{13608086400 + 3723.5, time.Date(2014, time.January, 3, 1, 2, 3, 500000000, time.UTC)},
+		{-86400, time.Date(1582, time.October, 13, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		if got := TimeFromSPSS(test.seconds); !got.Equal(test.want) {
+			t.Errorf("TimeFromSPSS(%f) got %s, want %s", test.seconds, got, test.want)
+		}
+		if got := TimeToSPSS(test.want); got != test.seconds {
+			t.Errorf("TimeToSPSS(%s) got %f, want %f", test.want, got, test.seconds)
+		}
+	}
+	if got := DurationFromSPSS(3723.25); got != time.Hour+2*time.Minute+3250*time.Millisecond {
+		t.Errorf("DurationFromSPSS got %s", got)
+	}
+}
+
+func TestReaderWithDates(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f, WithDates())
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	row, err := r.Read()
+	if err != nil {
+		t.Fatalf("failed to read case ::: err >>> %s", err)
+	}
+	if got, ok := row[1].(time.Time); !ok || !got.Equal(time.Date(2014, time.January, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v, want 2014-01-03", row[1])
+	}
+	if _, ok := row[0].(float64); !ok {
+		t.Errorf("got %T for a numeric variable, want float64", row[0])
+	}
+}
+
+func TestWriterDates(t *testing.T) {
+	when := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	d := &Dictionary{
+		Compression: Bytecode,
+		Variables: []*Variable{
+			{Name: "When", Numeric: true, Print: Format{Type: FormatDATETIME, Width: 20}},
+			{Name: "Took", Numeric: true, Print: Format{Type: FormatTIME, Width: 8}},
+			{Name: "Day", Numeric: true, Print: Format{Type: FormatWKDAY, Width: 9}},
+			{Name: "Missing", Numeric: true, Print: Format{Type: FormatDATE, Width: 11}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]Row{{when, 90 * time.Minute, time.Saturday, nil}}); err != nil {
+		t.Fatalf("failed to write ::: err >>> %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), WithDates())
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	row, err := r.Read()
+	if err != nil {
+		t.Fatalf("failed to read case ::: err >>> %s", err)
+	}
+	if got, ok := row[0].(time.Time); !ok || !got.Equal(when) {
+		t.Errorf("got %v, want %s", row[0], when)
+	}
+	if row[1] != 90*time.Minute || row[2] != time.Saturday || row[3] != nil {
+		t.Errorf("got %v", row)
+	}
+}
@@ -0,0 +1,263 @@
+package gospss
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+// Compression is the kind of compression applied to the case data of an
+// IBM SPSS Statistics file.
+type Compression int
+
+const (
+	// Uncompressed stores every case as raw 8 byte elements.
+	Uncompressed Compression = iota
+	// Bytecode stores the cases with simple bytecode compression.
+	Bytecode
+	// ZLib stores the cases with bytecode compression and then compresses
+	// the result in ZLIB blocks, this is the .zsav format.
+	ZLib
+)
+
+// String returns the name of the compression.
+func (c Compression) String() string {
+	switch c {
+	case Uncompressed:
+		return "uncompressed"
+	case Bytecode:
+		return "bytecode"
+	case ZLib:
+		return "zlib"
+	default:
+		return "unknown"
+	}
+}
+
+// Dictionary describes the metadata of an IBM SPSS Statistics file, that is
+// everything except the case data.
+type Dictionary struct {
+	// FileLabel is the label of the file. At most 64 bytes are stored.
+	FileLabel string
+
+	// Created is when the file was created. The file stores it without a
+	// time zone, so it is given in UTC. A Writer uses the current time if
+	// it is zero.
+	Created time.Time
+
+	// Product identifies the program that wrote the file, for example
+	// "IBM SPSS STATISTICS 64-bit MS Windows 22.0.0.0". At most 55 bytes
+	// are stored.
+	Product string
+
+	// NumCases is the number of cases in the file, or -1 if the file does
+	// not say. A Writer ignores it.
+	NumCases int64
+
+	// Compression is the kind of compression used for the case data.
+	Compression Compression
+
+	// Encoding is the name of the character encoding of the strings in the
+	// file, which a Reader transcodes to UTF-8, or "" if the file does not
+	// say. A Writer ignores it and always writes UTF-8.
+	Encoding string
+
+	// Weight is the variable that weights the cases, or nil if the cases
+	// are not weighted. It must be one of the numeric Variables.
+	Weight *Variable
+
+	// Variables are the variables of the file, in dictionary order.
+	Variables []*Variable
+
+	// Attributes are the custom attributes of the file by name, each with
+	// one value or, for an array attribute, several.
+	Attributes map[string][]string
+
+	// MRSets are the multiple response sets of the file.
+	MRSets []*MRSet
+
+	// Documents are the lines of the documents of the file, as added by the
+	// DOCUMENT and ADD DOCUMENT commands. At most 80 bytes of each line are
+	// stored.
+	Documents []string
+
+	// ProductInfo describes the program that wrote the file, in addition
+	// to Product.
+	ProductInfo string
+
+	// RawRecords are the extension records of the file that are not read
+	// in to any of the other fields, such as variable sets or trends. A
+	// Writer writes them as they are, in its own byte order, except for the
+	// subtypes it writes itself.
+	RawRecords []*RawRecord
+}
+
+// AddDocument appends text to the documents, a line for each line of text.
+// Lines longer than 80 bytes are wrapped.
+func (d *Dictionary) AddDocument(text ...string) {
+	for _, t := range text {
+		for _, line := range strings.Split(t, "\n") {
+			line = strings.TrimRight(line, " \r")
+			for len(line) > documentLineLen {
+				n := len(truncate(line, documentLineLen))
+				d.Documents = append(d.Documents, line[:n])
+				line = line[n:]
+			}
+			d.Documents = append(d.Documents, line)
+		}
+	}
+}
+
+// RawRecord is an extension record, record type 7, as stored in the file.
+type RawRecord struct {
+	// Subtype identifies the kind of record.
+	Subtype int32
+
+	// Size is the size of each element of Data in bytes.
+	Size int32
+
+	// Count is the number of elements in Data.
+	Count int32
+
+	// Data holds the elements, Size times Count bytes in ByteOrder.
+	Data []byte
+
+	// ByteOrder is the byte order of the file the record was read from. A
+	// Writer with another byte order reverses the bytes of each element of
+	// 2, 4 or 8 bytes. If it is nil, Data is in the byte order of the
+	// Writer.
+	ByteOrder binary.ByteOrder
+}
+
+// Role is the role of a variable in IBM SPSS Statistics dialogs that
+// support predefined roles.
+type Role int
+
+const (
+	RoleInput Role = iota
+	RoleTarget
+	RoleBoth
+	RoleNone
+	RolePartition
+	RoleSplit
+)
+
+// String returns the name of the role as shown by IBM SPSS Statistics.
+func (r Role) String() string {
+	switch r {
+	case RoleInput:
+		return "Input"
+	case RoleTarget:
+		return "Target"
+	case RoleBoth:
+		return "Both"
+	case RoleNone:
+		return "None"
+	case RolePartition:
+		return "Partition"
+	case RoleSplit:
+		return "Split"
+	default:
+		return "Unknown"
+	}
+}
+
+// Measure is the level of measurement of a variable.
+type Measure int
+
+const (
+	MeasureUnknown Measure = iota
+	MeasureNominal
+	MeasureOrdinal
+	MeasureScale
+)
+
+// String returns the name of the level of measurement as shown by IBM SPSS
+// Statistics.
+func (m Measure) String() string {
+	switch m {
+	case MeasureNominal:
+		return "Nominal"
+	case MeasureOrdinal:
+		return "Ordinal"
+	case MeasureScale:
+		return "Scale"
+	default:
+		return "Unknown"
+	}
+}
+
+// Alignment is the alignment of the values of a variable in the data
+// editor.
+type Alignment int
+
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCenter
+)
+
+// String returns the name of the alignment as shown by IBM SPSS Statistics.
+func (a Alignment) String() string {
+	switch a {
+	case AlignLeft:
+		return "Left"
+	case AlignRight:
+		return "Right"
+	case AlignCenter:
+		return "Center"
+	default:
+		return "Unknown"
+	}
+}
+
+// Dictionary returns the metadata of the file.
+func (r *Reader) Dictionary() *Dictionary {
+	return r.header.dictionary
+}
+
+// constrDictionary constructs the Dictionary from the raw header records and
+// the variables constructed from them.
+func (r *Reader) constrDictionary(h *Header) *Dictionary {
+	fh := h.Fileheader
+	d := &Dictionary{
+		FileLabel:   strings.TrimRight(r.decode(fh.fileLabel), " "),
+		Product:     strings.TrimSpace(strings.TrimPrefix(fh.prodName, "@(#)")),
+		NumCases:    int64(fh.ncases),
+		Compression: Compression(fh.compression),
+		Encoding:    r.encoding,
+		Variables:   h.metaData,
+	}
+	// The header only has room for an int32, so the extended count is
+	// preferred when it is known.
+	if h.ExtendedNCasesInfo != nil && h.ExtendedNCasesInfo.ncases >= 0 {
+		d.NumCases = h.ExtendedNCasesInfo.ncases
+	}
+	for _, mrsets := range []*multipleResponseSets{h.MultipleResponseSetsOld, h.MultipleResponseSetsNew} {
+		if mrsets != nil {
+			d.MRSets = append(d.MRSets, parseMRSets(mrsets.mrsets, d.Variables, r.decode)...)
+		}
+	}
+	d.RawRecords = h.RawRecords
+	if h.Documents != nil {
+		for _, line := range h.Documents.char {
+			d.Documents = append(d.Documents, strings.TrimRight(r.decode(line), " "))
+		}
+	}
+	if h.ExtraProductInfo != nil {
+		d.ProductInfo = strings.TrimRight(r.decode(h.ExtraProductInfo.info), " \x00")
+	}
+	if h.DataAttributes != nil {
+		d.Attributes, _ = parseAttributes(r.decode(h.DataAttributes.attributes))
+	}
+	if created, err := time.Parse("02 Jan 06 15:04:05", fh.creationDate+" "+fh.creationTime); err == nil {
+		d.Created = created
+	}
+	for _, v := range d.Variables {
+		// The weight index is 1-based.
+		if fh.weightIndex > 0 && v.n == int(fh.weightIndex)-1 {
+			d.Weight = v
+		}
+	}
+	return d
+}
@@ -0,0 +1,117 @@
+package gospss
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var ErrUnknownEncoding = errors.New("Unknown character encoding.")
+
+// WithEncoding makes a Reader decode names, labels and string values from the
+// named character encoding, such as "windows-1252" or "ISO-8859-2", instead
+// of the encoding given by the file. A Writer always writes UTF-8.
+func WithEncoding(name string) Option {
+	return func(o *options) {
+		o.encoding = name
+	}
+}
+
+// codePages maps the code pages of the machine integer info record to
+// encoding names, for files without a character encoding record.
+var codePages = map[int32]string{
+	2:     "US-ASCII",
+	874:   "windows-874",
+	932:   "Shift_JIS",
+	936:   "GBK",
+	949:   "EUC-KR",
+	950:   "Big5",
+	20127: "US-ASCII",
+	20866: "KOI8-R",
+	20932: "EUC-JP",
+	21866: "KOI8-U",
+	28591: "ISO-8859-1",
+	28592: "ISO-8859-2",
+	28593: "ISO-8859-3",
+	28594: "ISO-8859-4",
+	28595: "ISO-8859-5",
+	28596: "ISO-8859-6",
+	28597: "ISO-8859-7",
+	28598: "ISO-8859-8",
+	28599: "ISO-8859-9",
+	28603: "ISO-8859-13",
+	28605: "ISO-8859-15",
+	51932: "EUC-JP",
+	65001: "UTF-8",
+}
+
+// codePageName returns the encoding name of a code page, or "" if it is
+// unknown.
+func codePageName(code int32) string {
+	if code >= 1250 && code <= 1258 {
+		return "windows-" + strconv.Itoa(int(code))
+	}
+	return codePages[code]
+}
+
+// lookupEncoding returns the encoding with the name, which may be any of its
+// IANA or WHATWG names or aliases.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	name = strings.TrimSpace(name)
+	if e, err := htmlindex.Get(name); err == nil {
+		return e, nil
+	}
+	e, err := ianaindex.IANA.Encoding(name)
+	if err != nil || e == nil {
+		return nil, ErrUnknownEncoding
+	}
+	return e, nil
+}
+
+// setEncoding sets up the decoding of strings, from the encoding given by
+// the options, the character encoding record or the machine integer info
+// record, in that order. Files without any are read as UTF-8, as are files
+// with an unknown encoding, unless the encoding was given as an option.
+func (r *Reader) setEncoding(h *Header) error {
+	name := r.opts.encoding
+	if name == "" {
+		if h.CharacterEncoding != nil {
+			name = strings.TrimRight(h.CharacterEncoding.encoding, "\x00 ")
+		} else if h.MachineIntegerInfo != nil {
+			name = codePageName(h.MachineIntegerInfo.characterCode)
+		}
+	}
+	r.encoding = name
+	if name == "" {
+		return nil
+	}
+	e, err := lookupEncoding(name)
+	if err != nil {
+		if r.opts.encoding != "" {
+			return err
+		}
+		return nil
+	}
+	if e != unicode.UTF8 {
+		r.decoder = e.NewDecoder()
+	}
+	return nil
+}
+
+// decode returns s transcoded to UTF-8. Strings that cannot be decoded are
+// returned as they are.
+func (r *Reader) decode(s string) string {
+	if r.decoder == nil {
+		return s
+	}
+	d, err := r.decoder.String(s)
+	if err != nil {
+		return s
+	}
+	return d
+}
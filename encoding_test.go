@@ -0,0 +1,116 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	// Strings in windows-1252 in a file that claims to be in UTF-8, which
+	// only WithEncoding reads right: "Göteborg", "Malmö" and "Västerås".
+	d := testDictionary(Bytecode)
+	d.FileLabel = "Sm\xe5l\xe4nningar"
+	d.Variables[2].Label = "Ort d\xe4r man bor"
+	d.Variables[2].ValueLabels = []*ValueLabel{{Key: "G\xf6teborg", Value: "G\xf6teborg stad"}}
+	d.Variables[2].Missing = MissingSpec{Strings: []string{"Malm\xf6"}}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]Row{
+		{1.0, 1.0, "G\xf6teborg", "A"},
+		{2.0, 2.0, "Malm\xf6", "B"},
+		{3.0, 1.0, "V\xe4ster\xe5s", "C"},
+	}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), WithEncoding("windows-1252"), WithValueLabels(), WithMissing(MissingAsNaN))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	dict := r.Dictionary()
+	if dict.Encoding != "windows-1252" || dict.FileLabel != "Smålänningar" {
+		t.Errorf("got encoding %q and file label %q", dict.Encoding, dict.FileLabel)
+	}
+	city := dict.Variables[2]
+	if city.Label != "Ort där man bor" || city.ValueLabels[0].Key != "Göteborg" || city.ValueLabels[0].Value != "Göteborg stad" {
+		t.Errorf("unexpected variable %+v %+v", city, city.ValueLabels[0])
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if got, want := fmt.Sprint(rows), "[[1 Low Göteborg stad A] [2 High <nil> B] [3 Low Västerås C]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	r, err = NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if r.Dictionary().Encoding != "UTF-8" {
+		t.Errorf("got encoding %q, want UTF-8", r.Dictionary().Encoding)
+	}
+
+	if _, err := NewReader(bytes.NewReader(buf.Bytes()), WithEncoding("no-such-encoding")); err != ErrUnknownEncoding {
+		t.Errorf("got error %v, want %v", err, ErrUnknownEncoding)
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	// Older versions of IBM SPSS Statistics only give the code page in the
+	// machine integer record, newer ones the name in a record of its own.
+	for _, name := range []string{"", "windows-1252"} {
+		f := new(savFile)
+		f.header(2, 2)
+		f.variable("ID", 0, "")
+		f.variable("CITY", 8, "Ort d\xe4r man bor")
+		f.valueLabels(2, [2]string{"G\xf6teborg", "G\xf6teborg stad"})
+		f.machineInteger(1252)
+		f.machineFloatingPoint()
+		f.extension(13, 1, []byte("ID=ID\tCITY=Hemst\xe4d"))
+		if name != "" {
+			f.extension(20, 1, []byte(name))
+		}
+		f.end()
+		f.flt64s(1)
+		f.padded("G\xf6teborg", 8)
+		f.flt64s(2)
+		f.padded("Malm\xf6", 8)
+
+		r, err := NewReader(bytes.NewReader(f.Bytes()), WithValueLabels())
+		if err != nil {
+			t.Fatalf("%q: failed to read ::: err >>> %s", name, err)
+		}
+		dict := r.Dictionary()
+		city := dict.Variables[1]
+		if dict.Encoding != "windows-1252" || city.Name != "Hemstäd" || city.Label != "Ort där man bor" ||
+			city.ValueLabels[0].Key != "Göteborg" || city.ValueLabels[0].Value != "Göteborg stad" {
+			t.Errorf("%q: got encoding %q and variable %+v %+v", name, dict.Encoding, city, city.ValueLabels[0])
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%q: failed to read all records ::: err >>> %s", name, err)
+		}
+		if got, want := fmt.Sprint(rows), "[[1 Göteborg stad] [2 Malmö]]"; got != want {
+			t.Errorf("%q: got %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCodePageName(t *testing.T) {
+	for code, want := range map[int32]string{1250: "windows-1250", 1252: "windows-1252", 28592: "ISO-8859-2", 65001: "UTF-8", 4: ""} {
+		if got := codePageName(code); got != want {
+			t.Errorf("code page %d: got %q, want %q", code, got, want)
+		}
+		if want == "" {
+			continue
+		}
+		if _, err := lookupEncoding(want); err != nil {
+			t.Errorf("code page %d: failed to look up %s ::: err >>> %s", code, want, err)
+		}
+	}
+}
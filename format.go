@@ -0,0 +1,145 @@
+package gospss
+
+import "strconv"
+
+// FormatType is the type of a print or write format, as coded in the
+// variable record.
+type FormatType int
+
+const (
+	FormatA        FormatType = 1
+	FormatAHEX     FormatType = 2
+	FormatCOMMA    FormatType = 3
+	FormatDOLLAR   FormatType = 4
+	FormatF        FormatType = 5
+	FormatIB       FormatType = 6
+	FormatPIBHEX   FormatType = 7
+	FormatP        FormatType = 8
+	FormatPIB      FormatType = 9
+	FormatPK       FormatType = 10
+	FormatRB       FormatType = 11
+	FormatRBHEX    FormatType = 12
+	FormatZ        FormatType = 15
+	FormatN        FormatType = 16
+	FormatE        FormatType = 17
+	FormatDATE     FormatType = 20
+	FormatTIME     FormatType = 21
+	FormatDATETIME FormatType = 22
+	FormatADATE    FormatType = 23
+	FormatJDATE    FormatType = 24
+	FormatDTIME    FormatType = 25
+	FormatWKDAY    FormatType = 26
+	FormatMONTH    FormatType = 27
+	FormatMOYR     FormatType = 28
+	FormatQYR      FormatType = 29
+	FormatWKYR     FormatType = 30
+	FormatPCT      FormatType = 31
+	FormatDOT      FormatType = 32
+	FormatCCA      FormatType = 33
+	FormatCCB      FormatType = 34
+	FormatCCC      FormatType = 35
+	FormatCCD      FormatType = 36
+	FormatCCE      FormatType = 37
+	FormatEDATE    FormatType = 38
+	FormatSDATE    FormatType = 39
+	FormatMTIME    FormatType = 40
+	FormatYMDHMS   FormatType = 41
+)
+
+var formatNames = map[FormatType]string{
+	FormatA:        "A",
+	FormatAHEX:     "AHEX",
+	FormatCOMMA:    "COMMA",
+	FormatDOLLAR:   "DOLLAR",
+	FormatF:        "F",
+	FormatIB:       "IB",
+	FormatPIBHEX:   "PIBHEX",
+	FormatP:        "P",
+	FormatPIB:      "PIB",
+	FormatPK:       "PK",
+	FormatRB:       "RB",
+	FormatRBHEX:    "RBHEX",
+	FormatZ:        "Z",
+	FormatN:        "N",
+	FormatE:        "E",
+	FormatDATE:     "DATE",
+	FormatTIME:     "TIME",
+	FormatDATETIME: "DATETIME",
+	FormatADATE:    "ADATE",
+	FormatJDATE:    "JDATE",
+	FormatDTIME:    "DTIME",
+	FormatWKDAY:    "WKDAY",
+	FormatMONTH:    "MONTH",
+	FormatMOYR:     "MOYR",
+	FormatQYR:      "QYR",
+	FormatWKYR:     "WKYR",
+	FormatPCT:      "PCT",
+	FormatDOT:      "DOT",
+	FormatCCA:      "CCA",
+	FormatCCB:      "CCB",
+	FormatCCC:      "CCC",
+	FormatCCD:      "CCD",
+	FormatCCE:      "CCE",
+	FormatEDATE:    "EDATE",
+	FormatSDATE:    "SDATE",
+	FormatMTIME:    "MTIME",
+	FormatYMDHMS:   "YMDHMS",
+}
+
+// String returns the IBM SPSS Statistics name of the format type, such as
+// "F" or "DATETIME".
+func (t FormatType) String() string {
+	if name, ok := formatNames[t]; ok {
+		return name
+	}
+	return "FORMAT" + strconv.Itoa(int(t))
+}
+
+// hasDecimals reports whether formats of the type are written with their
+// number of decimals even when it is 0, as in F8.0.
+func (t FormatType) hasDecimals() bool {
+	switch t {
+	case FormatCOMMA, FormatDOLLAR, FormatF, FormatIB, FormatP, FormatPIB, FormatPK, FormatRB,
+		FormatZ, FormatE, FormatPCT, FormatDOT, FormatCCA, FormatCCB, FormatCCC, FormatCCD, FormatCCE:
+		return true
+	default:
+		return false
+	}
+}
+
+// Format is a print or write format of a variable. The print format is
+// used to display values, the write format to write them out as text.
+type Format struct {
+	// Type is the format type.
+	Type FormatType
+
+	// Width is the field width in characters.
+	Width int
+
+	// Decimals is the number of decimal places.
+	Decimals int
+}
+
+// String returns the format as written in IBM SPSS Statistics syntax, such
+// as "F8.2", "A20" or "DATE11".
+func (f Format) String() string {
+	s := f.Type.String() + strconv.Itoa(f.Width)
+	if f.Decimals > 0 || f.Type.hasDecimals() {
+		s += "." + strconv.Itoa(f.Decimals)
+	}
+	return s
+}
+
+// format returns the Format coded in a pw.
+func (p *pw) format() Format {
+	return Format{
+		Type:     FormatType(p.tpe),
+		Width:    int(p.width),
+		Decimals: int(p.decimal),
+	}
+}
+
+// code packs the format in to an int32 as stored in the variable record.
+func (f Format) code() int32 {
+	return printwrite(int(f.Type), f.Width, f.Decimals)
+}
@@ -0,0 +1,47 @@
+package gospss
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFormatString(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{Format{Type: FormatF, Width: 8, Decimals: 2}, "F8.2"},
+		{Format{Type: FormatF, Width: 8}, "F8.0"},
+		{Format{Type: FormatA, Width: 20}, "A20"},
+		{Format{Type: FormatDATE, Width: 11}, "DATE11"},
+		{Format{Type: FormatDATETIME, Width: 23, Decimals: 2}, "DATETIME23.2"},
+		{Format{Type: FormatCCA, Width: 10, Decimals: 1}, "CCA10.1"},
+	}
+	for _, test := range tests {
+		if got := test.format.String(); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestVariableFormats(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	variables := r.MetaData()
+	for i, want := range []string{"F8.2", "SDATE10", "F10.3"} {
+		if got := variables[i].Print.String(); got != want {
+			t.Errorf("variable %s: got print format %s, want %s", variables[i].Name, got, want)
+		}
+	}
+	if got := variables[2].Write.String(); got != "F8.2" {
+		t.Errorf("variable %s: got write format %s, want F8.2", variables[2].Name, got)
+	}
+}
@@ -0,0 +1,81 @@
+package gospss
+
+import "strings"
+
+// WithValueLabels makes a Reader return the label of a value instead of the
+// value if the variable has a label for it. Values without a label are
+// returned as they would be otherwise.
+func WithValueLabels() Option {
+	return func(o *options) {
+		o.labels = true
+	}
+}
+
+// LabelFor returns the label of value, a number for numeric variables or a
+// string for string variables, and reports whether there is one.
+func (v *Variable) LabelFor(value interface{}) (string, bool) {
+	key, ok := labelKey(value)
+	if !ok {
+		return "", false
+	}
+	for _, vl := range v.ValueLabels {
+		if k, ok := labelKey(vl.Key); ok && k == key {
+			return vl.Value, true
+		}
+	}
+	return "", false
+}
+
+// Labels returns a copy of row, a case of the variables, where each value
+// with a label is replaced by the label.
+func Labels(variables []*Variable, row Row) Row {
+	labeled := make(Row, len(row))
+	for i, value := range row {
+		labeled[i] = value
+		if i < len(variables) {
+			if label, ok := variables[i].LabelFor(value); ok {
+				labeled[i] = label
+			}
+		}
+	}
+	return labeled
+}
+
+// labelKey returns value in the form it has as the key of a value label
+// index, a float64 or a string without trailing spaces.
+func labelKey(value interface{}) (interface{}, bool) {
+	switch k := value.(type) {
+	case string:
+		return strings.TrimRight(k, " "), true
+	case MissingValue:
+		return labelKey(k.Value)
+	default:
+		f, err := toFloat64(value)
+		if err != nil {
+			return nil, false
+		}
+		return f, true
+	}
+}
+
+// indexedLabel returns the label of value in labels, an index built by
+// labelIndex, and reports whether there is one.
+func indexedLabel(labels map[interface{}]string, value interface{}) (string, bool) {
+	key, ok := labelKey(value)
+	if !ok {
+		return "", false
+	}
+	label, ok := labels[key]
+	return label, ok
+}
+
+// labelIndex returns an index of the labels of v by their keys.
+func labelIndex(v *Variable) map[interface{}]string {
+	labels := make(map[interface{}]string, len(v.ValueLabels))
+	for _, vl := range v.ValueLabels {
+		if key, ok := labelKey(vl.Key); ok {
+			labels[key] = vl.Value
+		}
+	}
+	return labels
+}
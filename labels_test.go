@@ -0,0 +1,132 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValueLabels(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.Variables[3].ValueLabels = []*ValueLabel{
+		{Key: "A", Value: "Alpha"},
+		{Key: "BCDE", Value: "Bravo"},
+	}
+	d.Variables[1].Missing = MissingSpec{Values: []float64{2.5}}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll(testRows); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	variables := r.Dictionary().Variables
+	if key := variables[1].ValueLabels[0].Key; key != 1.0 {
+		t.Errorf("got numeric key %#v, want 1.0", key)
+	}
+	if key := variables[3].ValueLabels[1].Key; key != "BCDE" {
+		t.Errorf("got string key %#v, want \"BCDE\"", key)
+	}
+	if label, ok := variables[1].LabelFor(2); !ok || label != "High" {
+		t.Errorf("got label %q %t for 2, want \"High\"", label, ok)
+	}
+	if _, ok := variables[1].LabelFor(2.5); ok {
+		t.Errorf("got a label for 2.5, want none")
+	}
+	// LabelFor sees the labels as they are now, not as they were read.
+	variables[1].ValueLabels = append(variables[1].ValueLabels, &ValueLabel{Key: 2.5, Value: "Middle"})
+	if label, ok := variables[1].LabelFor(2.5); !ok || label != "Middle" {
+		t.Errorf("got label %q %t for 2.5, want \"Middle\"", label, ok)
+	}
+	variables[1].ValueLabels = variables[1].ValueLabels[:len(variables[1].ValueLabels)-1]
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if got, want := fmt.Sprint(Labels(variables, rows[0])), "[1 Low Stockholm Alpha]"; got != want {
+		t.Errorf("got labeled row %s, want %s", got, want)
+	}
+
+	want := map[MissingPolicy]string{
+		MissingAsIs:    "[[1 Low Stockholm Alpha] [2 2.5 Rio de Janeiro city ] [3 NaN  Bravo] [-99 1.234567891e+06 Oslo F]]",
+		MissingWrapped: "[[1 Low Stockholm Alpha] [2 {2.5 false} Rio de Janeiro city ] [3 {NaN true}  Bravo] [-99 1.234567891e+06 Oslo F]]",
+	}
+	for policy, rows := range want {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()), WithValueLabels(), WithMissing(policy))
+		if err != nil {
+			t.Fatalf("policy %d: failed to read ::: err >>> %s", policy, err)
+		}
+		got, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("policy %d: failed to read all records ::: err >>> %s", policy, err)
+		}
+		if fmt.Sprint(got) != rows {
+			t.Errorf("policy %d: got %v, want %s", policy, got, rows)
+		}
+	}
+}
+
+func TestLongStringValueLabels(t *testing.T) {
+	// Open-ended coding with value labels on a string wider than 8 bytes,
+	// one longer than the 120 bytes the Writer writes.
+	service := "Service: " + strings.Repeat("friendly and knowledgeable staff, ", 4)
+	f := new(savFile)
+	f.header(5, 2)
+	f.variable("ID", 0, "")
+	f.variable("CODED", 24, "")
+	f.variable("RATING", 8, "")
+	// Value labels of the short string RATING, the fifth element.
+	f.valueLabels(5, [2]string{"E", "Excellent"}, [2]string{"G", "Good"})
+	f.machineInteger(65001)
+	f.machineFloatingPoint()
+	f.extension(13, 1, []byte("ID=ID\tCODED=FavouriteThingCoded\tRATING=Rating"))
+	labels := new(savFile)
+	labels.int32s(19)
+	labels.WriteString("FavouriteThingCoded")
+	labels.int32s(24, 2)
+	for _, vl := range [][2]string{{"service", service}, {"value for money", "Price"}} {
+		labels.int32s(24)
+		labels.padded(vl[0], 24)
+		labels.int32s(int32(len(vl[1])))
+		labels.WriteString(vl[1])
+	}
+	f.extension(21, 1, labels.Bytes())
+	f.end()
+	f.flt64s(1)
+	f.padded("service", 24)
+	f.padded("E", 8)
+	f.flt64s(2)
+	f.padded("value for money", 24)
+	f.padded("G", 8)
+
+	r, err := NewReader(bytes.NewReader(f.Bytes()), WithValueLabels())
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	variables := r.Dictionary().Variables
+	coded := variables[1]
+	if coded.Name != "FavouriteThingCoded" || coded.Width != 24 || len(coded.ValueLabels) != 2 {
+		t.Fatalf("unexpected variable %+v", coded)
+	}
+	if vl := coded.ValueLabels[1]; vl.Key != "value for money" || vl.Value != "Price" {
+		t.Errorf("got value label %+v, want value for money = Price", vl)
+	}
+	if len(variables[2].ValueLabels) != 2 {
+		t.Errorf("got %d short string value labels, want 2", len(variables[2].ValueLabels))
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	want := fmt.Sprint([]Row{{1.0, service, "Excellent"}, {2.0, "Price", "Good"}})
+	if fmt.Sprint(rows) != want {
+		t.Errorf("got %v, want %s", rows, want)
+	}
+}
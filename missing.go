@@ -0,0 +1,155 @@
+package gospss
+
+import (
+	"math"
+	"strings"
+)
+
+// MissingSpec describes the user-missing values of a variable. The zero
+// value means the variable has no user-missing values.
+type MissingSpec struct {
+	// Values are the discrete user-missing values of a numeric variable,
+	// at most 3, or 1 together with a range.
+	Values []float64
+
+	// Strings are the discrete user-missing values of a string variable,
+	// at most 3, without trailing spaces.
+	Strings []string
+
+	// Range is set if the values from Low to High, both included, are
+	// user-missing. Low is -Inf for LO and High is +Inf for HI.
+	Range bool
+	Low   float64
+	High  float64
+}
+
+// IsMissing reports whether value, a float64 or a string, is user-missing.
+func (m MissingSpec) IsMissing(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return m.isMissingNumber(v)
+	case string:
+		return m.isMissingString(v)
+	}
+	return false
+}
+
+// isMissingNumber reports whether the numeric value f is user-missing.
+func (m MissingSpec) isMissingNumber(f float64) bool {
+	if m.Range && f >= m.Low && f <= m.High {
+		return true
+	}
+	for _, mv := range m.Values {
+		if f == mv {
+			return true
+		}
+	}
+	return false
+}
+
+// isMissingString reports whether the string value s is user-missing.
+func (m MissingSpec) isMissingString(s string) bool {
+	s = strings.TrimRight(s, " ")
+	for _, mv := range m.Strings {
+		if s == mv {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingPolicy is how a Reader returns missing values.
+type MissingPolicy int
+
+const (
+	// MissingAsIs returns user-missing values as they are stored. The
+	// system-missing value is returned as NaN. This is the default.
+	MissingAsIs MissingPolicy = iota
+
+	// MissingAsNaN returns user-missing values of numeric variables as NaN,
+	// like the system-missing value, and of string variables as nil.
+	MissingAsNaN
+
+	// MissingWrapped returns system- and user-missing values wrapped in a
+	// MissingValue.
+	MissingWrapped
+)
+
+// MissingValue is how a Reader using MissingWrapped returns a missing
+// value.
+type MissingValue struct {
+	// Value is the value as it would have been returned with MissingAsIs.
+	Value interface{}
+
+	// System is true for the system-missing value and false for a
+	// user-missing value.
+	System bool
+}
+
+// WithMissing sets how a Reader returns missing values.
+func WithMissing(policy MissingPolicy) Option {
+	return func(o *options) {
+		o.missing = policy
+	}
+}
+
+// missingSpec constructs the MissingSpec of v from its variable record vr
+// and the long string missing values record.
+func (r *Reader) missingSpec(h *Header, v *Variable, vr *variabler) MissingSpec {
+	var m MissingSpec
+	if vr.tpe == 0 {
+		values := vr.missingValues
+		if vr.nMissingValues < 0 && len(values) >= 2 {
+			lowest, highest := lowestValue, highestValue
+			if h.MachineFloatingPoint != nil {
+				lowest, highest = h.MachineFloatingPoint.lowest, h.MachineFloatingPoint.highest
+			}
+			m.Range = true
+			m.Low, m.High = values[0], values[1]
+			if m.Low <= lowest {
+				m.Low = math.Inf(-1)
+			}
+			if m.High >= highest {
+				m.High = math.Inf(1)
+			}
+			values = values[2:]
+		}
+		m.Values = append(m.Values, values...)
+		return m
+	}
+	for _, s := range vr.missingStrings {
+		m.Strings = append(m.Strings, strings.TrimRight(r.decode(s), " "))
+	}
+	if h.LongStringMissingValues != nil {
+		for _, longMissing := range h.LongStringMissingValues.missings {
+			if r.namesVariable(longMissing.varName, v, vr) {
+				for _, mv := range longMissing.missingValues {
+					m.Strings = append(m.Strings, strings.TrimRight(r.decode(mv.value), " "))
+				}
+			}
+		}
+	}
+	return m
+}
+
+// applyMissing applies the missing value policy of the Reader to a value
+// of v. The value is a float64 or a string, converted is what Read returns
+// for it if it is not missing. It reports false if the value is not missing
+// or the policy is MissingAsIs.
+func (r *Reader) applyMissing(v *Variable, value interface{}, converted func() interface{}) (interface{}, bool) {
+	if r.opts.missing == MissingAsIs {
+		return nil, false
+	}
+	f, numeric := value.(float64)
+	system := numeric && math.IsNaN(f)
+	if !system && !v.Missing.IsMissing(value) {
+		return nil, false
+	}
+	if r.opts.missing == MissingWrapped {
+		return MissingValue{Value: converted(), System: system}, true
+	}
+	if numeric {
+		return math.NaN(), true
+	}
+	return nil, true
+}
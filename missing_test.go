@@ -0,0 +1,101 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestMissingValues(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.Variables[0].Missing = MissingSpec{Values: []float64{-99, -98, -97}}
+	d.Variables[1].Missing = MissingSpec{Range: true, Low: math.Inf(-1), High: 0, Values: []float64{99}}
+	d.Variables[2].Missing = MissingSpec{Strings: []string{"Oslo", "Unknown"}}
+	d.Variables[3].Missing = MissingSpec{Strings: []string{"F"}}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	rows := []Row{
+		{1.0, 1.5, "Stockholm", "A"},
+		{-99.0, -3.0, "Oslo", "F"},
+		{-97.0, 99.0, "Unknown", ""},
+		{math.NaN(), math.NaN(), "", "B"},
+	}
+	if err := w.WriteAll(rows); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	for i, v := range r.Dictionary().Variables {
+		if fmt.Sprint(v.Missing) != fmt.Sprint(d.Variables[i].Missing) {
+			t.Errorf("variable %s has missing values %+v, want %+v", v.Name, v.Missing, d.Variables[i].Missing)
+		}
+	}
+
+	want := map[MissingPolicy]string{
+		MissingAsIs:  "[[1 1.5 Stockholm A] [-99 -3 Oslo F] [-97 99 Unknown ] [NaN NaN  B]]",
+		MissingAsNaN: "[[1 1.5 Stockholm A] [NaN NaN <nil> <nil>] [NaN NaN <nil> ] [NaN NaN  B]]",
+		MissingWrapped: "[[1 1.5 Stockholm A] [{-99 false} {-3 false} {Oslo false} {F false}] " +
+			"[{-97 false} {99 false} {Unknown false} ] [{NaN true} {NaN true}  B]]",
+	}
+	for policy, rows := range want {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()), WithMissing(policy))
+		if err != nil {
+			t.Fatalf("policy %d: failed to read ::: err >>> %s", policy, err)
+		}
+		got, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("policy %d: failed to read all records ::: err >>> %s", policy, err)
+		}
+		if fmt.Sprint(got) != rows {
+			t.Errorf("policy %d: got %v, want %s", policy, got, rows)
+		}
+	}
+}
+
+func TestLongStringMissingValues(t *testing.T) {
+	// The missing values of a string wider than 8 bytes are in their own
+	// record, which names the variable by its long name.
+	f := new(savFile)
+	f.header(3, 3)
+	f.variable("ID", 0, "")
+	f.variable("CITYOFRE", 16, "")
+	f.machineInteger(65001)
+	f.machineFloatingPoint()
+	f.extension(13, 1, []byte("ID=ID\tCITYOFRE=CityOfResidence"))
+	missing := new(savFile)
+	missing.int32s(15)
+	missing.WriteString("CityOfResidence")
+	missing.WriteByte(2)
+	missing.int32s(8)
+	missing.padded("Unknown", 8)
+	missing.padded("NA", 8)
+	f.extension(22, 1, missing.Bytes())
+	f.end()
+	for i, city := range []string{"Stockholm", "Unknown", "NA"} {
+		f.flt64s(float64(i + 1))
+		f.padded(city, 16)
+	}
+
+	r, err := NewReader(bytes.NewReader(f.Bytes()), WithMissing(MissingAsNaN))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	city := r.Dictionary().Variables[1]
+	if city.Name != "CityOfResidence" || fmt.Sprint(city.Missing.Strings) != "[Unknown NA]" {
+		t.Errorf("got variable %s with missing values %v, want CityOfResidence with [Unknown NA]", city.Name, city.Missing.Strings)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if got, want := fmt.Sprint(rows), "[[1 Stockholm] [2 <nil>] [3 <nil>]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package gospss
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MRSetKind is the kind of a multiple response set.
+type MRSetKind int
+
+const (
+	// MRCategory is a multiple category set, where each variable holds one
+	// of the categories chosen.
+	MRCategory MRSetKind = iota
+
+	// MRDichotomy is a multiple dichotomy set, where each variable is one
+	// category that is chosen if it has the counted value, labeled by the
+	// variable labels (CATEGORYLABELS=VARLABELS).
+	MRDichotomy
+
+	// MRDichotomyCounted is a multiple dichotomy set labeled by the value
+	// labels of the counted value (CATEGORYLABELS=COUNTEDVALUES).
+	MRDichotomyCounted
+)
+
+// String returns the name of the kind as in the MRSETS command.
+func (k MRSetKind) String() string {
+	switch k {
+	case MRCategory:
+		return "MCGROUP"
+	case MRDichotomy:
+		return "MDGROUP VARLABELS"
+	case MRDichotomyCounted:
+		return "MDGROUP COUNTEDVALUES"
+	default:
+		return "unknown"
+	}
+}
+
+// MRSet is a multiple response set, a group of variables that together
+// hold the answers to a "select all that apply" question.
+type MRSet struct {
+	// Name is the name of the set, which begins with "$".
+	Name string
+
+	// Label is the label of the set.
+	Label string
+
+	// Kind is the kind of set.
+	Kind MRSetKind
+
+	// CountedValue is the value that counts as chosen in a dichotomy set,
+	// as a number in decimal digits for numeric variables.
+	CountedValue string
+
+	// LabelFromVariable is set if the set of kind MRDichotomyCounted takes
+	// its label from the label of its first variable (LABELSOURCE=VARLABEL).
+	LabelFromVariable bool
+
+	// Variables are the variables in the set.
+	Variables []*Variable
+}
+
+// parseMRSets parses the multiple response sets of a multiple response
+// sets record, one set per line, resolving the variables by their short
+// names. Sets that are malformed are skipped.
+//
+// The lengths of the counted strings are in bytes of the encoding of the
+// file, so s is parsed as it is in the file and decode transcodes the
+// names, labels and values parsed from it.
+func parseMRSets(s string, variables []*Variable, decode func(string) string) []*MRSet {
+	var sets []*MRSet
+	for _, line := range strings.Split(s, "\n") {
+		if set := parseMRSet(line, variables, decode); set != nil {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// parseMRSet parses one multiple response set, as in:
+//
+//	$name=C 10 Age groups age1 age2
+//	$name=D1 1 8 Channels tv radio web
+//	$name=E 11 1 1 0 tv radio web
+func parseMRSet(line string, variables []*Variable, decode func(string) string) *MRSet {
+	eq := strings.IndexByte(line, '=')
+	if eq < 1 || eq+1 >= len(line) {
+		return nil
+	}
+	set := &MRSet{Name: decode(strings.TrimSpace(line[:eq]))}
+	s := line[eq+2:]
+	var ok bool
+	switch line[eq+1] {
+	case 'C':
+		set.Kind = MRCategory
+		s = strings.TrimPrefix(s, " ")
+	case 'D':
+		set.Kind = MRDichotomy
+	case 'E':
+		set.Kind = MRDichotomyCounted
+		var flag string
+		flag, s = nextToken(s)
+		set.LabelFromVariable = flag == "11"
+	default:
+		return nil
+	}
+	if set.Kind != MRCategory {
+		if set.CountedValue, s, ok = countedString(s); !ok {
+			return nil
+		}
+		set.CountedValue = decode(strings.TrimRight(set.CountedValue, " "))
+	}
+	if set.Label, s, ok = countedString(s); !ok {
+		return nil
+	}
+	set.Label = decode(set.Label)
+	for {
+		var name string
+		if name, s = nextToken(s); name == "" {
+			break
+		}
+		// Both are the short names as they are in the file.
+		for _, v := range variables {
+			if strings.EqualFold(v.id, name) {
+				set.Variables = append(set.Variables, v)
+				break
+			}
+		}
+	}
+	return set
+}
+
+// countedString parses a string preceded by its length in bytes and a
+// space, like "5 hello", and returns it and what follows it.
+func countedString(s string) (string, string, bool) {
+	s = strings.TrimLeft(s, " ")
+	space := strings.IndexByte(s, ' ')
+	if space < 0 {
+		return "", s, false
+	}
+	n, err := strconv.Atoi(s[:space])
+	if err != nil || n < 0 || space+1+n > len(s) {
+		return "", s, false
+	}
+	return s[space+1 : space+1+n], s[space+1+n:], true
+}
+
+// nextToken returns the next space separated token of s and what follows it.
+func nextToken(s string) (string, string) {
+	s = strings.TrimLeft(s, " ")
+	if space := strings.IndexByte(s, ' '); space >= 0 {
+		return s[:space], s[space+1:]
+	}
+	return s, ""
+}
+
+// formatMRSet formats a multiple response set as in a multiple response
+// sets record, with the short names of its variables.
+func formatMRSet(set *MRSet, shorts map[*Variable]string) string {
+	var b strings.Builder
+	name := set.Name
+	if !strings.HasPrefix(name, "$") {
+		name = "$" + name
+	}
+	b.WriteString(name + "=")
+	counted := func(s string) {
+		b.WriteString(strconv.Itoa(len(s)) + " " + s)
+	}
+	label := set.Label
+	switch set.Kind {
+	case MRCategory:
+		b.WriteString("C ")
+	case MRDichotomy:
+		b.WriteString("D")
+		counted(set.CountedValue)
+		b.WriteString(" ")
+	case MRDichotomyCounted:
+		if set.LabelFromVariable {
+			b.WriteString("E 11 ")
+			label = ""
+		} else {
+			b.WriteString("E 1 ")
+		}
+		counted(set.CountedValue)
+		b.WriteString(" ")
+	}
+	counted(label)
+	for _, v := range set.Variables {
+		if short, ok := shorts[v]; ok {
+			b.WriteString(" " + strings.ToLower(short))
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestParseMRSets(t *testing.T) {
+	variables := []*Variable{{id: "TV", Name: "Television"}, {id: "RADIO", Name: "Radio"}, {id: "WEB", Name: "Web"}}
+	sets := parseMRSets("\n$media=C 14 Media channels tv radio web\n"+
+		"$seen=D8 1        4 Seen tv radio\n"+
+		"$used=E 11 1 1 0 radio web\n\n\n"+
+		"$broken=D1 1 99 No label\n", variables, func(s string) string { return s })
+	got := fmt.Sprint(len(sets))
+	for _, set := range sets {
+		got += fmt.Sprintf(" %s|%s|%s|%q|%t|%d", set.Name, set.Label, set.Kind, set.CountedValue, set.LabelFromVariable, len(set.Variables))
+	}
+	want := `3 $media|Media channels|MCGROUP|""|false|3 $seen|Seen|MDGROUP VARLABELS|"1"|false|2 $used||MDGROUP COUNTEDVALUES|"1"|true|2`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if sets[2].Variables[0] != variables[1] {
+		t.Errorf("got variable %+v, want %+v", sets[2].Variables[0], variables[1])
+	}
+}
+
+func TestMRSetsRoundTrip(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.MRSets = []*MRSet{
+		{Name: "$ids", Label: "Ids and scores", Kind: MRCategory, Variables: d.Variables[:2]},
+		{Name: "$codes", Label: "Coded", Kind: MRDichotomy, CountedValue: "A", Variables: d.Variables[2:]},
+		{Name: "$counted", Label: "Counted", Kind: MRDichotomyCounted, CountedValue: "1", Variables: d.Variables[:2]},
+	}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer ::: err >>> %s", err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	sets := r.Dictionary().MRSets
+	if len(sets) != len(d.MRSets) {
+		t.Fatalf("got %d sets, want %d", len(sets), len(d.MRSets))
+	}
+	for i, set := range sets {
+		want := d.MRSets[i]
+		if set.Name != want.Name || set.Label != want.Label || set.Kind != want.Kind || set.CountedValue != want.CountedValue ||
+			len(set.Variables) != len(want.Variables) || set.Variables[1].Name != want.Variables[1].Name {
+			t.Errorf("got set %+v, want %+v", set, want)
+		}
+	}
+}
+
+func TestMRSetsEncoding(t *testing.T) {
+	// The label "Sedda städer" is 12 bytes in windows-1252 and 13 in UTF-8.
+	f := new(savFile)
+	f.header(2, 0)
+	f.variable("STHLM", 0, "")
+	f.variable("G\xd6TEBORG", 0, "")
+	f.machineInteger(1252)
+	f.machineFloatingPoint()
+	f.extension(7, 1, []byte("$st\xe4der=D1 1 12 Sedda st\xe4der sthlm g\xf6teborg\n"))
+	f.extension(20, 1, []byte("windows-1252"))
+	f.end()
+
+	r, err := NewReader(bytes.NewReader(f.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	sets := r.Dictionary().MRSets
+	if len(sets) != 1 {
+		t.Fatalf("got %d sets, want 1", len(sets))
+	}
+	set := sets[0]
+	if set.Name != "$städer" || set.Label != "Sedda städer" || set.CountedValue != "1" || len(set.Variables) != 2 ||
+		set.Variables[1].Name != "GÖTEBORG" {
+		t.Errorf("got set %+v", set)
+	}
+}
@@ -0,0 +1,43 @@
+package gospss
+
+import "encoding/binary"
+
+// An Option changes how a Reader reads or a Writer writes a file.
+type Option func(*options)
+
+// options holds the settings made by the options given to NewReader,
+// NewReaderAt or NewWriter.
+type options struct {
+	// byteOrder overrides the byte order if it is set.
+	byteOrder binary.ByteOrder
+	// dates makes a Reader return date and time values as Go types.
+	dates bool
+	// missing is how a Reader returns missing values.
+	missing MissingPolicy
+	// labels makes a Reader return value labels instead of values.
+	labels bool
+	// encoding overrides the character encoding of a Reader if it is set.
+	encoding string
+	// progress is called every progressInterval cases read.
+	progress         func(read, total int64)
+	progressInterval int64
+}
+
+// newOptions applies opts to the default settings.
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithByteOrder sets the byte order, binary.LittleEndian or
+// binary.BigEndian. A Reader otherwise detects the byte order from the
+// layout code in the file header and confirms it with the machine integer
+// record, a Writer otherwise uses the byte order of the machine.
+func WithByteOrder(order binary.ByteOrder) Option {
+	return func(o *options) {
+		o.byteOrder = order
+	}
+}
@@ -0,0 +1,237 @@
+// Package parquet converts the cases of an IBM SPSS Statistics data file to
+// a Parquet file. It writes the records of the arrow package with the
+// Parquet writer of Apache Arrow, one row group per record, so that a file
+// of any size converts in bounded memory.
+package parquet
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	pq "github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/compress"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/hektorinho/gospss"
+	spssarrow "github.com/hektorinho/gospss/arrow"
+)
+
+var (
+	ErrClosed = errors.New("Parquet writer is closed.")
+	ErrCodec  = errors.New("Unknown compression codec.")
+)
+
+// DefaultRowGroupSize is the number of cases in a row group unless set with
+// WithRowGroupSize.
+const DefaultRowGroupSize = 16 * 1024
+
+// Codec is the compression codec of the pages of a file.
+type Codec int
+
+const (
+	Uncompressed Codec = 0
+	Snappy       Codec = 1
+	Gzip         Codec = 2
+)
+
+// codecs maps each Codec to the codec of the Parquet writer.
+var codecs = map[Codec]compress.Compression{
+	Uncompressed: compress.Codecs.Uncompressed,
+	Snappy:       compress.Codecs.Snappy,
+	Gzip:         compress.Codecs.Gzip,
+}
+
+// An Option changes how a Parquet file is written.
+type Option func(*options)
+
+// options holds the settings made by the options given to NewWriter or
+// WriteFile.
+type options struct {
+	// rowGroupSize is the number of cases in a row group.
+	rowGroupSize int
+	// codec compresses the pages.
+	codec Codec
+}
+
+// WithRowGroupSize sets the number of cases in a row group written by
+// WriteFile, which bounds the memory used to about that many cases.
+func WithRowGroupSize(n int) Option {
+	return func(o *options) {
+		o.rowGroupSize = n
+	}
+}
+
+// WithCompression sets the compression codec of the pages, Snappy unless
+// set.
+func WithCompression(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// newOptions applies opts to the default settings.
+func newOptions(opts []Option) *options {
+	o := &options{rowGroupSize: DefaultRowGroupSize, codec: Snappy}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WriteFile writes the cases of r to w as a Parquet file, in row groups of
+// the size set by WithRowGroupSize.
+//
+// Numeric variables are written as DOUBLE, string variables as STRING, and
+// variables with a date, date and time or time format as DATE, TIMESTAMP
+// in microseconds or INT64 microseconds, as converted by the arrow package.
+// Parquet has no type for durations and its TIME type holds a time of day,
+// while the value of a variable with a time format is any number of
+// seconds, such as 25 hours or a negative difference, so the microseconds
+// are written as plain 64 bit integers. Dates and times of SPSS have no
+// time zone, but the Parquet writer of Arrow marks every TIMESTAMP as
+// adjusted to UTC, so read them as UTC to get the times in the file back.
+// Missing values are written as nulls.
+//
+// The key-value metadata of the file holds the file label as
+// "spss.file_label", the character encoding of the file as
+// "spss.encoding", and the label, print format, measure and value labels
+// of each variable as "spss.variables", a JSON array like:
+//
+//	[{"name":"Score","label":"Satisfaction score","format":"F8.2","measure":"Ordinal","value_labels":{"1":"Low","2":"High"}}]
+func WriteFile(w io.Writer, r *gospss.Reader, opts ...Option) error {
+	o := newOptions(opts)
+	ar := spssarrow.NewReader(r, spssarrow.WithBatchSize(o.rowGroupSize))
+	pw, err := NewWriter(w, ar.Schema(), opts...)
+	if err != nil {
+		return err
+	}
+	for {
+		rec, err := ar.Read()
+		if err == io.EOF {
+			return pw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		err = pw.Write(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Writer writes records to a Parquet file, one row group per record.
+type Writer struct {
+	fw *pqarrow.FileWriter
+	// schema is the schema of the records as written, see fileSchema.
+	schema *arrow.Schema
+	closed bool
+}
+
+// NewWriter returns a Writer that writes records of schema to w. The
+// metadata of schema and of its fields becomes the key-value metadata of
+// the file, as described for WriteFile. The beginning of the file is
+// written right away.
+func NewWriter(w io.Writer, schema *arrow.Schema, opts ...Option) (*Writer, error) {
+	o := newOptions(opts)
+	codec, ok := codecs[o.codec]
+	if !ok {
+		return nil, ErrCodec
+	}
+	fs, err := fileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	props := pq.NewWriterProperties(pq.WithCompression(codec))
+	// The Parquet writer closes w on Close if it can, so hide that.
+	fw, err := pqarrow.NewFileWriter(fs, struct{ io.Writer }{w}, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{fw: fw, schema: fs}, nil
+}
+
+// Write writes a record, which has to be of the schema of the Writer, as a
+// row group.
+func (pw *Writer) Write(rec arrow.Record) error {
+	if pw.closed {
+		return ErrClosed
+	}
+	columns := make([]arrow.Array, rec.NumCols())
+	for i, a := range rec.Columns() {
+		if a.DataType().ID() == arrow.DURATION {
+			// Durations and 64 bit integers share the same layout.
+			d := a.Data()
+			data := array.NewData(arrow.PrimitiveTypes.Int64, d.Len(), d.Buffers(), nil, d.NullN(), d.Offset())
+			a = array.MakeFromData(data)
+			data.Release()
+			defer a.Release()
+		}
+		columns[i] = a
+	}
+	written := array.NewRecord(pw.schema, columns, rec.NumRows())
+	defer written.Release()
+	return pw.fw.Write(written)
+}
+
+// Close writes the footer of the file. It does not close the underlying
+// writer.
+func (pw *Writer) Close() error {
+	if pw.closed {
+		return ErrClosed
+	}
+	pw.closed = true
+	return pw.fw.Close()
+}
+
+// variable is the metadata of a variable in "spss.variables".
+type variable struct {
+	Name        string          `json:"name"`
+	Label       string          `json:"label,omitempty"`
+	Format      string          `json:"format,omitempty"`
+	Measure     string          `json:"measure,omitempty"`
+	ValueLabels json.RawMessage `json:"value_labels,omitempty"`
+}
+
+// fileSchema returns schema as it is written: with durations as 64 bit
+// integers, which the Parquet writer has no type for, and with the
+// metadata of the fields added to the metadata of the schema as
+// "spss.variables", which the Parquet writer writes as the key-value
+// metadata of the file.
+func fileSchema(schema *arrow.Schema) (*arrow.Schema, error) {
+	fields := schema.Fields()
+	variables := make([]variable, len(fields))
+	for i, f := range fields {
+		v := &variables[i]
+		v.Name = f.Name
+		v.Label = value(f.Metadata, "spss.label")
+		v.Format = value(f.Metadata, "spss.format")
+		v.Measure = value(f.Metadata, "spss.measure")
+		if labels := value(f.Metadata, "spss.value_labels"); labels != "" {
+			v.ValueLabels = json.RawMessage(labels)
+		}
+		if f.Type.ID() == arrow.DURATION {
+			fields[i].Type = arrow.PrimitiveTypes.Int64
+		}
+	}
+	b, err := json.Marshal(variables)
+	if err != nil {
+		return nil, err
+	}
+	md := schema.Metadata()
+	keys := append(append([]string(nil), md.Keys()...), "spss.variables")
+	values := append(append([]string(nil), md.Values()...), string(b))
+	meta := arrow.NewMetadata(keys, values)
+	return arrow.NewSchema(fields, &meta), nil
+}
+
+// value returns the value of key in m, or "" if it has none.
+func value(m arrow.Metadata, key string) string {
+	if i := m.FindKey(key); i >= 0 {
+		return m.Values()[i]
+	}
+	return ""
+}
@@ -0,0 +1,237 @@
+package parquet
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	pq "github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/file"
+	"github.com/apache/arrow/go/v14/parquet/schema"
+	"github.com/hektorinho/gospss"
+)
+
+func TestWriteFile(t *testing.T) {
+	when := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	d := &gospss.Dictionary{
+		FileLabel:   "gospss parquet test",
+		Compression: gospss.ZLib,
+		Variables: []*gospss.Variable{
+			{Name: "Score", Label: "Satisfaction score", Numeric: true, Print: gospss.Format{Type: gospss.FormatF, Width: 8, Decimals: 2},
+				Measure: gospss.MeasureOrdinal, ValueLabels: []*gospss.ValueLabel{{Key: 1.0, Value: "Low"}, {Key: 2.0, Value: "High"}}},
+			{Name: "City", Width: 20},
+			{Name: "Day", Numeric: true, Print: gospss.Format{Type: gospss.FormatDATE, Width: 11}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := gospss.NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]gospss.Row{
+		{1.0, "Stockholm", when},
+		{math.NaN(), "Oslo", nil},
+		{2.0, "Göteborg", when},
+	}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	r, err := gospss.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := WriteFile(out, r, WithRowGroupSize(2), WithCompression(Uncompressed)); err != nil {
+		t.Fatalf("failed to write file ::: err >>> %s", err)
+	}
+	pf, err := file.NewParquetReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read file ::: err >>> %s", err)
+	}
+	defer pf.Close()
+	if n := pf.NumRowGroups(); n != 2 {
+		t.Errorf("got %d row groups, want 2", n)
+	}
+	kvs := pf.MetaData().KeyValueMetadata()
+	for key, want := range map[string]string{
+		"spss.file_label": "gospss parquet test",
+		"spss.variables":  `[{"name":"Score","label":"Satisfaction score","format":"F8.2","measure":"Ordinal","value_labels":{"1":"Low","2":"High"}},{"name":"City","format":"A20","measure":"Nominal"},{"name":"Day","format":"DATE11","measure":"Scale"}]`,
+	} {
+		if got := kvs.FindValue(key); got == nil || *got != want {
+			t.Errorf("got %s %v, want %s", key, got, want)
+		}
+	}
+	if got, want := readColumn(t, pf, 0), []string{"1", "null", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got scores %q, want %q", got, want)
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "Score", Type: arrow.PrimitiveTypes.Float64, Nullable: true}}, nil)
+	if _, err := NewWriter(new(bytes.Buffer), schema, WithCompression(Codec(9))); err != ErrCodec {
+		t.Errorf("got error %v, want %v", err, ErrCodec)
+	}
+	pw, err := NewWriter(new(bytes.Buffer), schema)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("failed to close writer ::: err >>> %s", err)
+	}
+	if err := pw.Close(); err != ErrClosed {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+}
+
+// readColumn returns the values of the j'th column of every row group of
+// pf as strings, "null" for nulls.
+func readColumn(t *testing.T, pf *file.Reader, j int) []string {
+	var values []string
+	for i := 0; i < pf.NumRowGroups(); i++ {
+		rg := pf.RowGroup(i)
+		n := rg.NumRows()
+		cr, err := rg.Column(j)
+		if err != nil {
+			t.Fatalf("failed to read column ::: err >>> %s", err)
+		}
+		levels := make([]int16, n)
+		var read []string
+		switch cr := cr.(type) {
+		case *file.Float64ColumnChunkReader:
+			v := make([]float64, n)
+			_, m, err := cr.ReadBatch(n, v, levels, nil)
+			if err != nil {
+				t.Fatalf("failed to read values ::: err >>> %s", err)
+			}
+			for _, f := range v[:m] {
+				read = append(read, strconv.FormatFloat(f, 'f', -1, 64))
+			}
+		case *file.Int32ColumnChunkReader:
+			v := make([]int32, n)
+			_, m, err := cr.ReadBatch(n, v, levels, nil)
+			if err != nil {
+				t.Fatalf("failed to read values ::: err >>> %s", err)
+			}
+			for _, d := range v[:m] {
+				read = append(read, strconv.Itoa(int(d)))
+			}
+		case *file.Int64ColumnChunkReader:
+			v := make([]int64, n)
+			_, m, err := cr.ReadBatch(n, v, levels, nil)
+			if err != nil {
+				t.Fatalf("failed to read values ::: err >>> %s", err)
+			}
+			for _, d := range v[:m] {
+				read = append(read, strconv.FormatInt(d, 10))
+			}
+		case *file.ByteArrayColumnChunkReader:
+			v := make([]pq.ByteArray, n)
+			_, m, err := cr.ReadBatch(n, v, levels, nil)
+			if err != nil {
+				t.Fatalf("failed to read values ::: err >>> %s", err)
+			}
+			for _, b := range v[:m] {
+				read = append(read, string(b))
+			}
+		default:
+			t.Fatalf("got column reader %T", cr)
+		}
+		// Only the values that are not null are read, as marked by the
+		// definition levels.
+		for _, level := range levels {
+			if level == 0 {
+				values = append(values, "null")
+			} else {
+				values, read = append(values, read[0]), read[1:]
+			}
+		}
+	}
+	return values
+}
+
+func TestReadBack(t *testing.T) {
+	when := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	d := &gospss.Dictionary{
+		FileLabel:   "gospss parquet test",
+		Compression: gospss.Bytecode,
+		Variables: []*gospss.Variable{
+			{Name: "Score", Numeric: true, Print: gospss.Format{Type: gospss.FormatF, Width: 8, Decimals: 2},
+				ValueLabels: []*gospss.ValueLabel{{Key: 1.0, Value: "Low"}}},
+			{Name: "City", Width: 20},
+			{Name: "Day", Numeric: true, Print: gospss.Format{Type: gospss.FormatDATE, Width: 11}},
+			{Name: "When", Numeric: true, Print: gospss.Format{Type: gospss.FormatDATETIME, Width: 20}},
+			{Name: "Took", Numeric: true, Print: gospss.Format{Type: gospss.FormatTIME, Width: 11, Decimals: 2}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := gospss.NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]gospss.Row{
+		{1.0, "Stockholm", when, when, 90*time.Minute + 1500*time.Millisecond},
+		{math.NaN(), "Oslo", nil, nil, nil},
+		{2.5, "Göteborg", when.AddDate(-100, 0, 0), when.AddDate(-100, 0, 0), -25 * time.Hour},
+	}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+
+	for _, codec := range []Codec{Uncompressed, Snappy, Gzip} {
+		r, err := gospss.NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to read ::: err >>> %s", err)
+		}
+		out := new(bytes.Buffer)
+		if err := WriteFile(out, r, WithRowGroupSize(2), WithCompression(codec)); err != nil {
+			t.Fatalf("failed to write file ::: err >>> %s", err)
+		}
+
+		pf, err := file.NewParquetReader(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to read file with codec %d ::: err >>> %s", codec, err)
+		}
+		if n := pf.NumRowGroups(); n != 2 {
+			t.Errorf("got %d row groups, want 2", n)
+		}
+		if label := pf.MetaData().KeyValueMetadata().FindValue("spss.file_label"); label == nil || *label != "gospss parquet test" {
+			t.Errorf("got file label %v", label)
+		}
+		columns := pf.MetaData().Schema
+		for i, want := range []struct {
+			physical pq.Type
+			logical  schema.LogicalType
+		}{
+			{pq.Types.Double, schema.NoLogicalType{}},
+			{pq.Types.ByteArray, schema.StringLogicalType{}},
+			{pq.Types.Int32, schema.DateLogicalType{}},
+			{pq.Types.Int64, schema.NewTimestampLogicalType(true, schema.TimeUnitMicros)},
+			{pq.Types.Int64, schema.NewIntLogicalType(64, true)},
+		} {
+			c := columns.Column(i)
+			if c.PhysicalType() != want.physical || !c.LogicalType().Equals(want.logical) {
+				t.Errorf("got column %s of %s %s, want %s %s", c.Name(), c.PhysicalType(), c.LogicalType(), want.physical, want.logical)
+			}
+		}
+
+		day := func(t time.Time) string {
+			return strconv.FormatInt(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()/86400, 10)
+		}
+		micros := func(t time.Time) string {
+			return strconv.FormatInt(t.UnixMicro(), 10)
+		}
+		for j, want := range [][]string{
+			{"1", "null", "2.5"},
+			{"Stockholm", "Oslo", "Göteborg"},
+			{day(when), "null", day(when.AddDate(-100, 0, 0))},
+			{micros(when), "null", micros(when.AddDate(-100, 0, 0))},
+			{"5401500000", "null", "-90000000000"},
+		} {
+			if got := readColumn(t, pf, j); !reflect.DeepEqual(got, want) {
+				t.Errorf("got column %d %q with codec %d, want %q", j, got, codec, want)
+			}
+		}
+		pf.Close()
+	}
+}
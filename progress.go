@@ -0,0 +1,39 @@
+package gospss
+
+// NumCases returns the number of cases in the file and reports whether the
+// file says. The 64-bit count of the extended number of cases record is
+// preferred over the one of the file header.
+func (r *Reader) NumCases() (int64, bool) {
+	n := r.Dictionary().NumCases
+	return n, n >= 0
+}
+
+// CasesRead returns the number of cases read so far. After SeekCase(n) it
+// is n.
+func (r *Reader) CasesRead() int64 {
+	return r.caseNum
+}
+
+// WithProgress makes a Reader call fn every interval cases read, with the
+// number of cases read so far and the number of cases in the file, or -1
+// if the file does not say. An interval of less than 1 means every case.
+func WithProgress(interval int64, fn func(read, total int64)) Option {
+	return func(o *options) {
+		if interval < 1 {
+			interval = 1
+		}
+		o.progress = fn
+		o.progressInterval = interval
+	}
+}
+
+// reportProgress calls the progress function, if any, when another interval
+// of cases has been read. It is called by the methods that return cases, not
+// for the cases SeekCase and BuildCaseIndex read to find their way.
+func (r *Reader) reportProgress() {
+	if r.opts.progress == nil || r.caseNum%r.opts.progressInterval != 0 {
+		return
+	}
+	total, _ := r.NumCases()
+	r.opts.progress(r.caseNum, total)
+}
@@ -0,0 +1,86 @@
+package gospss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestNumCasesAndProgress(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+	var progress []string
+	r, err := NewReader(f, WithProgress(1000, func(read, total int64) {
+		progress = append(progress, fmt.Sprintf("%d/%d", read, total))
+	}))
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	if n, ok := r.NumCases(); n != 3742 || !ok {
+		t.Errorf("got %d cases %t, want 3742", n, ok)
+	}
+	if _, err := r.ReadAll(); err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if r.CasesRead() != 3742 || fmt.Sprint(progress) != "[1000/3742 2000/3742 3000/3742]" {
+		t.Errorf("got %d cases read and progress %v", r.CasesRead(), progress)
+	}
+
+	// The cases read to build the index and to seek are not reported.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	progress = nil
+	r, err = NewReader(f, WithProgress(1, func(read, total int64) {
+		progress = append(progress, fmt.Sprintf("%d/%d", read, total))
+	}))
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	if err := r.BuildCaseIndex(100); err != nil {
+		t.Fatalf("failed to build the case index ::: err >>> %s", err)
+	}
+	if err := r.SeekCase(1999); err != nil {
+		t.Fatalf("failed to seek ::: err >>> %s", err)
+	}
+	if _, err := r.ReadBatch(2); err != nil {
+		t.Fatalf("failed to read a batch ::: err >>> %s", err)
+	}
+	if fmt.Sprint(progress) != "[2000/3742 2001/3742]" {
+		t.Errorf("got progress %v", progress)
+	}
+
+	// A file written to a stream does not know its number of cases, unless
+	// it has an extended number of cases record.
+	for _, extended := range []bool{false, true} {
+		d := testDictionary(Bytecode)
+		if extended {
+			data := make([]byte, 16)
+			binary.LittleEndian.PutUint64(data, 1)
+			binary.LittleEndian.PutUint64(data[8:], uint64(len(testRows)))
+			d.RawRecords = []*RawRecord{{Subtype: 16, Size: 8, Count: 2, Data: data}}
+		}
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, d, WithByteOrder(binary.LittleEndian))
+		if err != nil {
+			t.Fatalf("failed to create writer ::: err >>> %s", err)
+		}
+		if err := w.WriteAll(testRows); err != nil {
+			t.Fatalf("failed to write rows ::: err >>> %s", err)
+		}
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to read ::: err >>> %s", err)
+		}
+		n, ok := r.NumCases()
+		if extended && (n != int64(len(testRows)) || !ok) || !extended && (n != -1 || ok) {
+			t.Errorf("extended %t: got %d cases %t", extended, n, ok)
+		}
+	}
+}
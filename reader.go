@@ -8,51 +8,161 @@ import (
 	"errors"
 	"io"
 	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"unsafe"
+
+	"golang.org/x/text/encoding"
 )
 
 var (
 	err                 error
 	ErrNotValidSPSSFile = errors.New("Not a valid IBM SPSS Statistics file.")
+	ErrUnknownRecord    = errors.New("Unknown record type in the dictionary.")
+	ErrByteOrder        = errors.New("Byte order of the file header and the machine integer record differ.")
 )
 
 // A Reader reads data from an IBM SPSS Statistics encoded system file
 //
 // As returned by NewReader blabla
 type Reader struct {
-	// Endianess indicates the byte order, default is set to binary.LittleEndian.
-	// The package can not infer this on it's own so it has to be supplied if the
-	// file has a different byte order than Little Endian.
-	// Have never seen, but heard it's possible to have BigEndian.
+	// Endianess indicates the byte order. It is detected from the layout
+	// code in the file header unless forced with WithByteOrder, and falls
+	// back to the byte order of the machine. Either is confirmed by the
+	// machine integer record.
 	endianess binary.ByteOrder
+	// guessed is set if the layout code did not tell the byte order.
+	guessed bool
+	// opts are the options given to NewReader.
+	opts *options
 	// Using bufio reader to buffer through the data file.
 	r *bufio.Reader
+	// src counts the bytes r has read from the data file.
+	src *countingReader
 	// header is reading in the metadata of the file.
 	header *Header
 	// zlib is to see if the file is zlib compressed.
 	zlib bool
-	// If the data file i zlib compressed we will push the data in to an
-	// uncompressed reader.
-	uncompressed *bytes.Reader
+	// If the data file is zlib compressed the data is read through z, which
+	// decompresses one block at a time.
+	z *zlibReader
+	// seeker is set if the data file can be seeked, start is the offset of
+	// the file header in it.
+	seeker io.Seeker
+	start  int64
+	// opcodes is the current 8 byte block of bytecode compression opcodes and
+	// opcodeIndex the index of the next one to evaluate. A block of opcodes
+	// can span several cases, so it has to outlive a single readDataRecord.
+	opcodes     []byte
+	opcodeIndex int
+	// dataStart is the position of the first case, see dataPos.
+	dataStart int64
+	// caseNum is the number of the next case to be read, counting from 0.
+	caseNum int64
+	// index holds the position of every indexInterval'th case of a
+	// compressed file, as built by BuildCaseIndex.
+	index         []*caseIndexEntry
+	indexInterval int64
+	// encoding is the name of the character encoding of the strings in the
+	// file and decoder transcodes them to UTF-8, nil if they already are.
+	encoding string
+	decoder  *encoding.Decoder
+	// fields caches how the fields of struct types map on to the variables,
+	// for ReadInto.
+	fields map[reflect.Type][]*structField
+	// labels indexes the value labels of each variable by their keys, to
+	// look up the labels of the values read. LabelFor searches the value
+	// labels instead, which the caller can change.
+	labels map[*Variable]map[interface{}]string
+	// selection holds the variables set by Select, nil if all are read, and
+	// columns the index in a row of the value of each variable, -1 if it is
+	// skipped.
+	selection []*Variable
+	columns   []int
 }
 
 // NewReader returns a new Reader that reads from r
-func NewReader(r io.Reader) (*Reader, error) {
+func NewReader(r io.Reader, opts ...Option) (*Reader, error) {
+	src := &countingReader{r: r}
 	sav := &Reader{
-		// Set default endianess to Little Endian as it is most commonly used.
-		endianess: machineEndianess(),
-		r:         bufio.NewReader(r),
+		endianess:   machineEndianess(),
+		opts:        newOptions(opts),
+		r:           bufio.NewReader(src),
+		src:         src,
+		opcodeIndex: 8,
+	}
+	if sav.opts.byteOrder != nil {
+		sav.endianess = sav.opts.byteOrder
+	}
+	if s, ok := r.(io.Seeker); ok {
+		// Pipes are files too, but can not be seeked.
+		if start, err := s.Seek(0, io.SeekCurrent); err == nil {
+			sav.seeker = s
+			sav.start = start
+		}
 	}
 	sav.header, err = sav._header()
+	if err != nil && sav.guessed && sav.seeker != nil {
+		// The byte order of the machine was a guess, so the file is read
+		// again in the other byte order.
+		sav.endianess = otherEndianess(sav.endianess)
+		sav.zlib, sav.z = false, nil
+		if err := sav.seek(0); err != nil {
+			return nil, err
+		}
+		sav.header, err = sav._header()
+	}
 	if err != nil {
 		return nil, err
 	}
 	return sav, nil
 }
 
+// countingReader counts the number of bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// offset returns the offset in the data file of the next unread byte.
+func (r *Reader) offset() int64 {
+	return r.src.n - int64(r.r.Buffered())
+}
+
+// seek positions r at offset in the data file.
+func (r *Reader) seek(offset int64) error {
+	if _, err := r.seeker.Seek(r.start+offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.src.n = offset
+	r.r.Reset(r.src)
+	return nil
+}
+
+// readAt returns n bytes at offset in the data file and leaves r where it was.
+func (r *Reader) readAt(offset int64, n int) ([]byte, error) {
+	current := r.offset()
+	if err := r.seek(offset); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, err
+	}
+	return b, r.seek(current)
+}
+
 // Change endianess, can be binary.LittleEndian (most common) or binary.BigEndian
+//
+// Deprecated: the byte order is detected from the file header before it is
+// parsed. Use the WithByteOrder option to force a byte order.
 func (r *Reader) ChangeEndianess(endianess binary.ByteOrder) {
 	r.endianess = endianess
 }
@@ -80,7 +190,9 @@ type Header struct {
 	DictionaryTermination   *dictionaryTermination
 	ZLibDataHeader          *zLibDataHeader
 	ZLibDataTrailer         *zLibDataTrailer
+	RawRecords              []*RawRecord
 	metaData                []*Variable
+	dictionary              *Dictionary
 }
 
 // Constants used to determine the type of record.
@@ -143,6 +255,9 @@ func (r *Reader) Read() (Row, error) {
 }
 
 // HeaderData returns all raw header data from the IBM SPSS Statistics file.
+//
+// Deprecated: the fields of the raw records are not exported, use
+// Dictionary to get the metadata of the file.
 func (r *Reader) Header() *Header {
 	return r.header
 }
@@ -197,7 +312,7 @@ func (r *Reader) _header() (*Header, error) {
 				return nil, err
 			}
 		case r.checkNextRecord(variableDisplayRecord):
-			h.VariableDisplay, err = r.readVariableDisplay()
+			h.VariableDisplay, err = r.readVariableDisplay(h.Variable)
 			if err != nil {
 				return nil, err
 			}
@@ -253,49 +368,45 @@ func (r *Reader) _header() (*Header, error) {
 			}
 			metadata = false
 		default:
-			_, err := r.r.Discard(1)
-			if err == io.EOF {
-				metadata = false
+			raw, err := r.readRawRecord()
+			if err != nil {
+				return nil, err
 			}
+			h.RawRecords = append(h.RawRecords, raw)
 		}
 	}
+	if err := r.checkEndianess(h); err != nil {
+		return nil, err
+	}
 
-	// Uncompress the data if it is a zsav file
+	// Set up decompression of the data if it is a zsav file. The trailer
+	// comes after the data, so it can only be read up front if the file can
+	// be seeked. Otherwise it is read once all blocks are decompressed.
 	if h.Fileheader.compression == 2 {
 		h.ZLibDataHeader, err = r.readZLibHeader()
 		if err != nil {
 			return nil, err
 		}
-
-		rc, err := zlib.NewReader(r.r)
-		if err != nil {
-			return nil, err
-		}
-		defer rc.Close()
-
-		var uncompressed []byte
-		for {
-			tb := make([]byte, 32768)
-			_, err = rc.Read(tb)
-			if err == io.EOF {
-				uncompressed = append(uncompressed, tb...)
-				break
+		if r.seeker != nil {
+			b, err := r.readAt(h.ZLibDataHeader.zTrailerOffset, int(h.ZLibDataHeader.zTrailerLength))
+			if err != nil {
+				return nil, err
 			}
+			h.ZLibDataTrailer, err = r.parseZLibTrailer(b)
 			if err != nil {
 				return nil, err
 			}
-			uncompressed = append(uncompressed, tb...)
-		}
-		r.uncompressed = bytes.NewReader(uncompressed)
-
-		h.ZLibDataTrailer, err = r.readZLibTrailer()
-		if err != nil {
-			return nil, err
 		}
+		r.z = &zlibReader{r: r, header: h, pos: h.ZLibDataHeader.zHeaderOffset}
 		r.zlib = true
 	}
+	r.dataStart = r.dataPos()
+	if err := r.setEncoding(h); err != nil {
+		return nil, err
+	}
 	// Construct the meta data.
 	h.metaData = r.constrVariables(h)
+	h.dictionary = r.constrDictionary(h)
 
 	return h, nil
 }
@@ -317,9 +428,9 @@ func (r *Reader) checkNextRecord(input []int32) bool {
 func (r *Reader) readBytes(n int) ([]byte, error) {
 	lb := make([]byte, n)
 	if r.zlib {
-		_, err = r.uncompressed.Read(lb)
+		_, err = io.ReadFull(r.z, lb)
 	} else {
-		_, err = r.r.Read(lb)
+		_, err = io.ReadFull(r.r, lb)
 	}
 	if err != nil {
 		return nil, err
@@ -433,6 +544,17 @@ func (r *Reader) readFileheader() (*fileHeader, error) {
 	if fh.prodName, err = r.readString(60); err != nil {
 		return nil, err
 	}
+	if r.opts.byteOrder == nil {
+		layout, err := r.r.Peek(4)
+		if err != nil {
+			return nil, err
+		}
+		if order := layoutEndianess(layout); order != nil {
+			r.endianess = order
+		} else {
+			r.guessed = true
+		}
+	}
 	if fh.layoutCode, err = r.readInt32(); err != nil {
 		return nil, err
 	}
@@ -529,6 +651,9 @@ type variabler struct {
 	// in the range. When a range plus a value are present, the third
 	// element denotes the additional discrete missing value.
 	missingValues []float64
+
+	// The same elements as missingValues, as strings for string variables.
+	missingStrings []string
 }
 
 type pw struct {
@@ -547,16 +672,11 @@ func (r *Reader) readVariabler() ([]*variabler, error) {
 
 	// utility function for this function only.
 	printwrite := func(b []byte) (*pw, error) {
-		var v3 int32
-		if int(b[3]) == 0 {
-			v3 = int32(b[2])
-		} else {
-			v3 = int32(b[2])*10 + int32(b[3])
-		}
+		code := r.endianess.Uint32(b)
 		return &pw{
-			decimal: int32(b[0]),
-			width:   int32(b[1]),
-			tpe:     v3,
+			decimal: int32(code & 0xff),
+			width:   int32(code >> 8 & 0xff),
+			tpe:     int32(code >> 16 & 0xff),
 		}, nil
 	}
 
@@ -615,18 +735,21 @@ func (r *Reader) readVariabler() ([]*variabler, error) {
 			if vr.label, err = r.readString(int(vr.labelLen) + add); err != nil {
 				return nil, err
 			}
+			vr.label = vr.label[:vr.labelLen]
 		}
-		if vr.nMissingValues > 0 {
-			for i := 0; i < int(vr.nMissingValues); i++ {
-				mv, err := r.readFlt64()
-				if err != nil {
-					return nil, err
-				}
-				vr.missingValues = append(vr.missingValues, mv)
+		// A range of missing values is given by a negative number.
+		nMissingValues := vr.nMissingValues
+		if nMissingValues < 0 {
+			nMissingValues = -nMissingValues
+		}
+		for i := 0; i < int(nMissingValues); i++ {
+			b, err := r.readBytes(8)
+			if err != nil {
+				return nil, err
 			}
+			vr.missingValues = append(vr.missingValues, math.Float64frombits(r.endianess.Uint64(b)))
+			vr.missingStrings = append(vr.missingStrings, string(b))
 		}
-		// TODO: find out why I need this here.
-		r.r.Peek(2000)
 		v = append(v, vr)
 		if !r.checkNext(2) {
 			return v, nil
@@ -656,6 +779,9 @@ type vl struct {
 	// following value label variables record (see below) is read.
 	value float64
 
+	// The same 8 bytes as value, as a string for string variables.
+	raw string
+
 	// The label's length, in bytes. The documented maximum length varies
 	// from 60 to 120 based on SPSS version.
 	labelLen int32
@@ -685,8 +811,6 @@ type vlvr struct {
 func (r *Reader) readValueLabels() ([]*valueLabel, error) {
 	var m []*valueLabel
 	for {
-		// TODO: Find out why this i needed.
-		r.r.Peek(2000)
 		n := new(valueLabel)
 		n.recType, err = r.readInt32()
 		if err != nil {
@@ -698,20 +822,24 @@ func (r *Reader) readValueLabels() ([]*valueLabel, error) {
 		}
 		for i := int32(0); i < n.labelCount; i++ {
 			v1 := new(vl)
-			// TODO: Make a check for string or float, value should be stored as a string and not a float.
-			v1.value, err = r.readFlt64()
+			// The type of the value is not known until the variables are, so
+			// it is kept both as a number and as a string.
+			b, err := r.readBytes(8)
 			if err != nil {
 				return nil, err
 			}
+			v1.value = math.Float64frombits(r.endianess.Uint64(b))
+			v1.raw = string(b)
 			b1, err := r.readBytes(1)
 			if err != nil {
 				return nil, err
 			}
-			v1.labelLen = calcLen(int(b1[0]), 8)
-			v1.label, err = r.readString(int(v1.labelLen))
+			v1.labelLen = int32(b1[0])
+			v1.label, err = r.readString(int(calcLen(int(b1[0]), 8)))
 			if err != nil {
 				return nil, err
 			}
+			v1.label = v1.label[:v1.labelLen]
 			n.labels = append(n.labels, v1)
 		}
 		valrec := new(vlvr)
@@ -731,10 +859,10 @@ func (r *Reader) readValueLabels() ([]*valueLabel, error) {
 			valrec.vars = append(valrec.vars, v2)
 		}
 		n.vlvr = valrec
+		m = append(m, n)
 		if !r.checkNext(3) {
 			break
 		}
-		m = append(m, n)
 	}
 	return m, nil
 }
@@ -768,6 +896,9 @@ type documents struct {
 	char []string
 }
 
+// documentLineLen is the length of a document line.
+const documentLineLen = 80
+
 // readDocuments returns a pointer to a documents and an error.
 func (r *Reader) readDocuments() (*documents, error) {
 	doc := new(documents)
@@ -780,7 +911,7 @@ func (r *Reader) readDocuments() (*documents, error) {
 		return nil, err
 	}
 	for i := 0; i < int(doc.nLines); i++ {
-		char, err := r.readString(80)
+		char, err := r.readString(documentLineLen)
 		if err != nil {
 			return nil, err
 		}
@@ -1038,6 +1169,39 @@ func (r *Reader) readMultipleResponseSets() (*multipleResponseSets, error) {
 	return m, nil
 }
 
+// readRawRecord returns a pointer to a RawRecord and an error. Any extension
+// record can be read this way as its header gives its length.
+func (r *Reader) readRawRecord() (*RawRecord, error) {
+	recType, err := r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	if recType != 7 {
+		return nil, ErrUnknownRecord
+	}
+	m := &RawRecord{ByteOrder: r.endianess}
+	m.Subtype, err = r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	m.Size, err = r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	m.Count, err = r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	if m.Size < 0 || m.Count < 0 || int64(m.Size)*int64(m.Count) > math.MaxInt32 {
+		return nil, ErrUnknownRecord
+	}
+	m.Data, err = r.readBytes(int(m.Size * m.Count))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // If present, the extra product info record of the IBM SPSS Statistics file.
 type extraProductInfo struct {
 	// Record type. Always set to 7.
@@ -1094,7 +1258,8 @@ type variableDisplay struct {
 	size int32
 
 	// The number of sets of variable display parameters (ordinarily the
-	// number of variables in the dictionary), times 2 or 3.
+	// number of variables in the dictionary), times 2 or 3. Very long
+	// strings have a set for each segment.
 	count int32
 
 	// List of the struct display.
@@ -1103,6 +1268,7 @@ type variableDisplay struct {
 
 type display struct {
 	// The measurement type of the variable:
+	// 0: Unknown
 	// 1: Nominal scale
 	// 2: Ordinal scale
 	// 3: Continuous scale
@@ -1122,7 +1288,9 @@ type display struct {
 }
 
 // readVariableDisplay returns a pointer to a variabledisplay and an error.
-func (r *Reader) readVariableDisplay() (*variableDisplay, error) {
+// The variable records tell whether the sets of display parameters have 2
+// or 3 fields.
+func (r *Reader) readVariableDisplay(variables []*variabler) (*variableDisplay, error) {
 	m := new(variableDisplay)
 	m.recType, err = r.readInt32()
 	if err != nil {
@@ -1140,22 +1308,32 @@ func (r *Reader) readVariableDisplay() (*variableDisplay, error) {
 	if err != nil {
 		return nil, err
 	}
-	//m.count is the number of records times 2 or 3, only seen 3 in practise.
-	//TODO: make test to check if it is times 2 or 3.
-	for i := 0; i < int(m.count/3); i++ {
-		d := new(display)
-		d.measure, err = r.readInt32()
-		if err != nil {
-			return nil, err
+	// There is a set for each variable record that is not a continuation.
+	var n int32
+	for _, vr := range variables {
+		if vr.tpe >= 0 {
+			n++
 		}
-		d.width, err = r.readInt32()
+	}
+	// Older files omit the width, leaving 2 fields in each set.
+	fields := int32(3)
+	if m.count != 3*n && (m.count == 2*n || m.count%3 != 0) {
+		fields = 2
+	}
+	values := make([]int32, m.count)
+	for i := range values {
+		values[i], err = r.readInt32()
 		if err != nil {
 			return nil, err
 		}
-		d.alignment, err = r.readInt32()
-		if err != nil {
-			return nil, err
+	}
+	for i := int32(0); i+fields <= m.count; i += fields {
+		d := new(display)
+		d.measure = values[i]
+		if fields == 3 {
+			d.width = values[i+1]
 		}
+		d.alignment = values[i+fields-1]
 		m.display = append(m.display, d)
 	}
 	return m, nil
@@ -1197,8 +1375,6 @@ type varNamePairs struct {
 // readLongVariableNames returns a pointer to a longvariablenames and an error.
 func (r *Reader) readLongVariableNames() (*longVariableNames, error) {
 	m := new(longVariableNames)
-	// TODO: Look in to the peek.
-	r.r.Peek(2000)
 	m.recType, err = r.readInt32()
 	if err != nil {
 		return nil, err
@@ -1230,6 +1406,15 @@ func (r *Reader) readLongVariableNames() (*longVariableNames, error) {
 	return m, nil
 }
 
+// Very long strings, wider than 255 bytes, are stored as segments of
+// segmentWidth bytes of which the first segmentLen are used, and a last
+// segment with what remains.
+const (
+	maxStringWidth = 32767
+	segmentWidth   = 255
+	segmentLen     = 252
+)
+
 // If present, the very long string record of the IBM SPSS Statistics file.
 type veryLongString struct {
 	// Record type. Always set to 7.
@@ -1294,9 +1479,12 @@ func (r *Reader) readVeryLongString() (*veryLongString, error) {
 			continue
 		}
 		pair := new(stringLength)
-		tuple := bytes.Split(s, []byte{61})
+		tuple := bytes.SplitN(s, []byte{61}, 2)
+		if len(tuple) != 2 {
+			continue
+		}
 		pair.key = string(tuple[0])
-		pair.value = string(tuple[1])
+		pair.value = strings.Trim(string(tuple[1]), "\x00 ")
 		m.stringLength = append(m.stringLength, pair)
 	}
 	return m, nil
@@ -1506,7 +1694,8 @@ type missings struct {
 }
 
 type missingValues struct {
-	// The length of the missing value string, in bytes.  This value should
+	// The length of the missing value string, in bytes. It is stored once,
+	// in front of all missing values of the variable. This value should
 	// be 8, because long string variables are at least 8 bytes wide (by
 	// definition), only the first 8 bytes of a long string variable's
 	// missing values are allowed to be non-spaces, and any spaces within the
@@ -1557,17 +1746,21 @@ func (r *Reader) readLongStringMissingValues() (*longStringMissingValues, error)
 		}
 		n.nMissingValues = int32(b1[0])
 		i++
+		// The length of the values is given once for all of them.
+		valueLen, err := r.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		i += 4
 		var os []*missingValues
 		for j := 0; j < int(n.nMissingValues); j++ {
 			o := new(missingValues)
-			o.valueLen, err = r.readInt32()
-			if err != nil {
-				return nil, err
-			}
+			o.valueLen = valueLen
 			o.value, err = r.readString(int(o.valueLen))
 			if err != nil {
 				return nil, err
 			}
+			i += int(o.valueLen)
 			os = append(os, o)
 		}
 		n.missingValues = os
@@ -1592,30 +1785,14 @@ type dataAttributes struct {
 	// The total number of bytes in attributes.
 	count int32
 
-	// The attributes, in a text-based format.
+	// The attributes, in a text-based format, see parseAttributes and
+	// parseVariableAttributes.
 	attributes string
-
-	// Used in the variable attributes to extract variables and their role.
-	roles []*role
-}
-
-type role struct {
-	// Name of the variable.
-	name string
-	// Role of the variable.
-	// 0: Input
-	// 1: output
-	// 2: Both
-	// 3: None
-	// 4: Partition
-	// 5: Split
-	role int
 }
 
 // readDataAttributes returns a pointer to a dataattributes and an error.
 func (r *Reader) readDataAttributes() (*dataAttributes, error) {
 	m := new(dataAttributes)
-	r.r.Peek(2102)
 	m.recType, err = r.readInt32()
 	if err != nil {
 		return nil, err
@@ -1636,21 +1813,6 @@ func (r *Reader) readDataAttributes() (*dataAttributes, error) {
 	if err != nil {
 		return nil, err
 	}
-	if m.subtype == 18 {
-		m.attributes = strings.Replace(m.attributes, "\x0A", "", -1)
-		atr := strings.Split(m.attributes, "/")
-		var roles []*role
-		for _, a := range atr {
-			r := new(role)
-			r2 := strings.Split(a, ":")
-			r.name = r2[0]
-			r2[1] = strings.TrimLeft(r2[1], "$@Role('")
-			r2[1] = strings.TrimRight(r2[1], "')")
-			r.role, _ = strconv.Atoi(r2[1])
-			roles = append(roles, r)
-		}
-		m.roles = roles
-	}
 	return m, nil
 }
 
@@ -1729,7 +1891,7 @@ func (r *Reader) readDictionaryTermination() (*dictionaryTermination, error) {
 
 // Variable struct is a collection of useful information from the native spss structs.
 type Variable struct {
-	// n is the variable record index. Used as a helper to extract childVariables.
+	// n is the variable record index, which is the dictionary index minus 1.
 	n int
 
 	// id is the 8 byte char from the variable record.
@@ -1741,87 +1903,179 @@ type Variable struct {
 	// Label is the variable label.
 	Label string
 
-	// Decimal is the number of decimals in the variable.
+	// Decimal is the number of decimals in the variable, as in the print format.
 	Decimal int
 
-	// Width is the number of bytes used for the variable.
+	// Width is the number of bytes of the values of a string variable, as
+	// stored in the file, and the width of the print format of a numeric
+	// variable.
 	Width int
 
 	// Numeric is a bool if true the variable is a numeric variable.
 	Numeric bool
 
-	// Type is the variable type.
+	// Type is the variable type, the FormatType of the print format.
 	Type int
 
+	// Print is the format used to display values of the variable.
+	Print Format
+
+	// Write is the format used to write values of the variable as text.
+	Write Format
+
 	// List of missing value and long missing value strings.
+	//
+	// Deprecated: Use Missing.
 	MissingValues []interface{}
 
+	// Missing are the user-missing values of the variable.
+	Missing MissingSpec
+
 	// List of value labels from variable record and LongValueLabels.
 	ValueLabels []*ValueLabel
 
-	// The measurement of the variable, (1) Nominal, (2) Ordinal or (3) Continuous.
-	Measure int
+	// Measure is the level of measurement of the variable.
+	Measure Measure
+
+	// DisplayWidth is the width of the column of the variable in the data
+	// editor, in characters, or 0 if the file does not say.
+	DisplayWidth int
 
-	// Helper to conclude how many child variables a variable has.
-	childVariables int
+	// Alignment is the alignment of the values in the column.
+	Alignment Alignment
+
+	// Role is the predefined role of the variable, RoleInput if the file
+	// does not say.
+	Role Role
+
+	// Attributes are the custom attributes of the variable by name, each
+	// with one value or, for an array attribute, several. The role is not
+	// among them, it is Role.
+	Attributes map[string][]string
+
+	// segments are the widths of the variable records a string variable is
+	// stored in, more than one for a very long string.
+	segments []int
+}
 
-	// chunks keeps track of how many variable record chunks it requires to build variable
-	chunks int
+// ShortName returns the name of the variable from the variable record, of
+// at most 8 bytes, or Name if the variable was not read from a file.
+func (v *Variable) ShortName() string {
+	if v.id == "" {
+		return v.Name
+	}
+	return v.id
 }
 
-// ValueLabel ...
+// ValueLabel is a label for a single value of a variable.
 type ValueLabel struct {
-	Key   interface{}
+	// Key is the value being labeled, a float64 for numeric variables and a
+	// string without trailing spaces for string variables.
+	Key interface{}
+
+	// Value is the label.
 	Value string
 }
 
 // Construct Variables from an existing spss struct.
 func (r *Reader) constrVariables(h *Header) []*Variable {
 	var variables []*Variable
+	r.labels = make(map[*Variable]map[interface{}]string)
+
+	// The widths of the very long strings by the names of their first
+	// segments.
+	veryLong := make(map[string]int)
+	if h.VeryLongString != nil {
+		for _, pair := range h.VeryLongString.stringLength {
+			if width, err := strconv.Atoi(pair.value); err == nil {
+				veryLong[strings.ToUpper(pair.key)] = width
+			}
+		}
+	}
+	// skip is the number of segments of the last very long string that are
+	// still to be added to it.
+	var skip int
+
+	// segment is the index of the variable record among those that are not
+	// continuations, which is the index of its display parameters.
+	segment := -1
 
 	for i, vr := range h.Variable {
 		v := new(Variable)
+		if vr.tpe >= 0 {
+			segment++
+		}
+		if vr.tpe >= 0 && skip > 0 {
+			parent := variables[len(variables)-1]
+			parent.segments = append(parent.segments, int(vr.tpe))
+			skip--
+			continue
+		}
 		if vr.tpe >= 0 {
 			v.n = i
 			v.id = vr.char
-			v.Name = vr.char
+			v.Name = r.decode(vr.char)
 			if h.LongVariableNames != nil {
 				for _, longName := range h.LongVariableNames.varNamePairs {
 					if strings.ToLower(vr.char) == strings.ToLower(longName.key) {
-						v.Name = longName.value
+						v.Name = r.decode(longName.value)
 					}
 				}
 			}
 			if vr.hasVarLabel > 0 {
-				v.Label = vr.label
+				v.Label = r.decode(vr.label)
 			}
 			v.Decimal = int(vr.print.decimal)
 			v.Width = int(vr.print.width)
-			if r.header != nil {
-				v.Measure = int(r.header.VariableDisplay.display[i].measure)
+			if vr.tpe > 0 {
+				// The print width of a string can differ from its
+				// width, such as twice it for AHEX.
+				v.Width = int(vr.tpe)
+			}
+			if h.VariableDisplay != nil && segment < len(h.VariableDisplay.display) {
+				d := h.VariableDisplay.display[segment]
+				v.Measure = Measure(d.measure)
+				v.DisplayWidth = int(d.width)
+				v.Alignment = Alignment(d.alignment)
 			}
 			if vr.tpe == 0 {
 				v.Numeric = true
 			}
 			v.Type = int(vr.print.tpe)
+			v.Print = vr.print.format()
+			v.Write = vr.write.format()
+			if vr.tpe > 0 {
+				v.segments = []int{int(vr.tpe)}
+				if width, ok := veryLong[strings.ToUpper(vr.char)]; ok && width > segmentWidth {
+					v.Width = width
+					v.Print.Width, v.Write.Width = width, width
+					skip = (width+segmentLen-1)/segmentLen - 1
+				}
+			}
 			if vr.nMissingValues > 0 {
 				v.MissingValues = append(v.MissingValues, vr.missingValues)
 			}
 			if h.LongStringMissingValues != nil {
 				for _, longMissing := range h.LongStringMissingValues.missings {
-					if strings.ToLower(vr.char) == strings.ToLower(longMissing.varName) {
+					if r.namesVariable(longMissing.varName, v, vr) {
 						v.MissingValues = append(v.MissingValues, longMissing.missingValues)
 					}
 				}
 			}
+			v.Missing = r.missingSpec(h, v, vr)
 			if h.ValueLabel != nil {
 				for _, valLabel := range h.ValueLabel {
 					for _, chk := range valLabel.vlvr.vars {
-						if v.n == int(chk) {
+						// Dictionary indexes are 1-based.
+						if v.n == int(chk)-1 {
 							for _, lbs := range valLabel.labels {
 								vl := new(ValueLabel)
-								vl.Key = strconv.FormatFloat(lbs.value, 'f', int(vr.print.decimal), 64)
-								vl.Value = lbs.label
+								if v.Numeric {
+									vl.Key = lbs.value
+								} else {
+									vl.Key = strings.TrimRight(r.decode(lbs.raw), " ")
+								}
+								vl.Value = r.decode(lbs.label)
 								v.ValueLabels = append(v.ValueLabels, vl)
 							}
 						}
@@ -1830,116 +2084,123 @@ func (r *Reader) constrVariables(h *Header) []*Variable {
 			}
 			if h.LongStringValueLabels != nil {
 				for _, longValueLabel := range h.LongStringValueLabels.valueLabelPairs {
-					if strings.ToLower(vr.char) == strings.ToLower(longValueLabel.varName) {
+					if r.namesVariable(longValueLabel.varName, v, vr) {
 						for _, lbs := range longValueLabel.longLabels {
 							vl := new(ValueLabel)
-							vl.Key = lbs.value
-							vl.Value = lbs.label
+							vl.Key = strings.TrimRight(r.decode(lbs.value), " ")
+							vl.Value = r.decode(lbs.label)
+							v.ValueLabels = append(v.ValueLabels, vl)
 						}
 					}
 				}
 			}
+			r.labels[v] = labelIndex(v)
 			variables = append(variables, v)
-		} else {
-			if variables != nil && len(variables) > 0 {
-				//variables[len(variables)-1].Width += int(vr.print.width)
-			}
 		}
 	}
 
-	type subVariable struct {
-		index  int
-		parent int
-		width  int
-	}
-
-	//Clean out superflouos data columns
-	findParent := func(variables []*Variable) []*Variable {
-		// Get the real variables from the role list.
-		var realVariables []int
-		for _, role := range h.VariableAttributes.roles {
-			for _, v := range variables {
-				if strings.ToLower(role.name) == strings.ToLower(v.Name) {
-					realVariables = append(realVariables, v.n)
-				}
-			}
-		}
-		//
-		intIn := func(n int, set []int) bool {
-			for _, element := range set {
-				if n == element {
-					return true
-				}
-			}
-			return false
-		}
-		//
-		var vs []*Variable
-		var subVars []*subVariable
-		for _, element := range variables {
-			var v *Variable
-			if intIn(element.n, realVariables) {
-				v = element
-				vs = append(vs, v)
-				continue
-			}
-			compareIndex := 999999999
-			closestIndex := 0
-			for _, elm := range variables {
-				if ((element.n - elm.n) < compareIndex) && ((element.n - elm.n) > 0) && intIn(elm.n, realVariables) {
-					compareIndex = element.n - elm.n
-					closestIndex = elm.n
+	if h.VariableAttributes != nil {
+		attributes := parseVariableAttributes(r.decode(h.VariableAttributes.attributes))
+		for _, v := range variables {
+			v.Attributes = attributes[strings.ToLower(v.Name)]
+			// The role is kept in an attribute, 0 to 5 as the Role
+			// constants.
+			if role, ok := v.Attributes[roleAttribute]; ok {
+				if len(role) > 0 {
+					n, _ := strconv.Atoi(strings.TrimSpace(role[0]))
+					v.Role = Role(n)
 				}
+				delete(v.Attributes, roleAttribute)
 			}
-			sub := &subVariable{
-				index:  element.n,
-				parent: closestIndex,
-				width:  element.Width,
+			if len(v.Attributes) == 0 {
+				v.Attributes = nil
 			}
-			subVars = append(subVars, sub)
 		}
-
-		//
-		for _, element := range vs {
-			for _, subelement := range subVars {
-				if element.n == subelement.parent {
-					element.childVariables++
-					element.Width += subelement.width
-				}
-			}
-			element.Width -= element.childVariables * 3
-		}
-		return vs
-	}
-	if h.VariableAttributes != nil {
-		variables = findParent(variables)
 	}
 	return variables
 }
 
+// namesVariable reports whether name, as stored in a long string value
+// labels or missing values record, names v with the variable record vr.
+// The records name variables by their long names, but some writers use the
+// short names.
+func (r *Reader) namesVariable(name string, v *Variable, vr *variabler) bool {
+	return strings.EqualFold(r.decode(name), v.Name) || strings.EqualFold(name, vr.char)
+}
+
 // readDataRecord takes an existing Spss struct and returns a
 // list of list of data and an error.
 func (r *Reader) readDataRecord() (Row, error) {
+	row, err := r.readCase()
+	if err != nil {
+		return nil, err
+	}
+	r.reportProgress()
+	for i, v := range r.Selected() {
+		switch value := row[i].(type) {
+		case float64:
+			row[i] = r.numericValue(v, value)
+		case string:
+			row[i] = r.stringValue(v, value)
+		}
+	}
+	return row, nil
+}
+
+// readCase reads the next case and returns the values of the selected
+// variables as they are stored, a float64 for a numeric variable, NaN for
+// the system-missing value, and a string without padding for a string
+// variable. The values of variables that are not selected are skipped.
+func (r *Reader) readCase() (Row, error) {
+	row := make(Row, len(r.Selected()))
+	if err := r.scanCase(rowSink(row)); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// caseSink receives the values of the selected variables of a case from
+// scanCase, by the index of their value in a row.
+type caseSink interface {
+	setNumber(col int, f float64)
+	setString(col int, s string)
+}
+
+// rowSink stores the values of a case in a Row.
+type rowSink Row
+
+func (s rowSink) setNumber(col int, f float64)  { s[col] = f }
+func (s rowSink) setString(col int, str string) { s[col] = str }
+
+// scanCase reads the next case and passes the values of the selected
+// variables to sink, as described for readCase.
+func (r *Reader) scanCase(sink caseSink) error {
 
-	// buf is the chunk of 8 bytes to initiate each read with, bufindex is the current index.
-	// cases is the list of list with cases.
-	// var cases []Data
-	var buf []byte
-	bufindex := 8
 	var chunksToRead int
 	var charsToRead int
 
-	for {
-		var row Row
-		for _, Var := range r.header.metaData {
-			// Define a few helper variables
-			var numData float64
-			var strData string
-
+	if len(r.header.metaData) == 0 {
+		return io.EOF
+	}
+	for i, Var := range r.header.metaData {
+		// Define a few helper variables
+		var numData float64
+		var strData string
+		col := r.column(i)
+		skip := col < 0
+
+		// A numeric variable is read as a single element, a string
+		// variable segment by segment.
+		segments := Var.segments
+		if Var.Numeric {
+			segments = []int{0}
+		}
+		for s, width := range segments {
+			var segData string
 			if Var.Numeric {
 				chunksToRead = 1
 			} else {
-				charsToRead = Var.Width
+				charsToRead = width
 				chunksToRead = int(math.Floor(float64(charsToRead-1)/8.0 + 1.0))
 			}
 
@@ -1947,44 +2208,38 @@ func (r *Reader) readDataRecord() (Row, error) {
 				switch r.header.Fileheader.compression {
 				case 0:
 					// Add uncompressed support
-					if Var.Numeric {
-						numData, err = r.readFlt64()
-						if err == io.EOF {
-							return row, io.EOF
+					if skip {
+						if err := r.skipBytes(8); err != nil {
+							return err
 						}
+					} else if Var.Numeric {
+						numData, err = r.readFlt64()
 						if err != nil {
-							return nil, err
+							return err
 						}
 						if numData == r.header.MachineFloatingPoint.sysmis {
 							numData = math.NaN()
 						}
 					} else {
 						txt, err := r.readString(8)
-						if err == io.EOF {
-							return row, io.EOF
-						}
 						if err != nil {
-							return nil, err
+							return err
 						}
-						strData = txt
+						segData += txt
 						charsToRead -= 8
 					}
 				case 1, 2:
 					// Byte compressed data.
-					if bufindex > 7 {
-						r.r.Peek(2000)
-						buf, err = r.readBytes(8)
-						if err == io.EOF {
-							return row, io.EOF
-						}
+					if r.opcodeIndex > 7 {
+						r.opcodes, err = r.readBytes(8)
 						if err != nil {
-							return nil, err
+							return err
 						}
-						bufindex = 0
+						r.opcodeIndex = 0
 					}
 					// The current byte value we are evaluating.
-					byteValue := int(buf[bufindex])
-					bufindex++
+					byteValue := int(r.opcodes[r.opcodeIndex])
+					r.opcodeIndex++
 
 					switch byteValue {
 					// 0: Should be ignored.
@@ -1995,35 +2250,32 @@ func (r *Reader) readDataRecord() (Row, error) {
 					case 0:
 						continue
 					case 252:
-						return row, nil
+						return io.EOF
 					case 253:
-						if Var.Numeric {
-							numData, err = r.readFlt64()
-							if err == io.EOF {
-								return row, io.EOF
+						if skip {
+							if err := r.skipBytes(8); err != nil {
+								return err
 							}
+						} else if Var.Numeric {
+							numData, err = r.readFlt64()
 							if err != nil {
-								return nil, err
+								return err
 							}
 						} else {
 							chunkStringLen := int(math.Min(8.0, float64(charsToRead)))
-							t, err := r.readString(chunkStringLen)
-							if err == io.EOF {
-								return row, io.EOF
-							}
+							t, err := r.readString(8)
 							if err != nil {
-								return nil, err
-							}
-							strData += t
-
-							if charsToRead < 8 {
-								r.r.Discard(8 - charsToRead)
+								return err
 							}
-
+							segData += t[:chunkStringLen]
 							charsToRead -= chunkStringLen
 						}
 					case 254:
-						strData += ""
+						if !skip {
+							chunkStringLen := int(math.Min(8.0, float64(charsToRead)))
+							segData += strings.Repeat(" ", chunkStringLen)
+							charsToRead -= chunkStringLen
+						}
 					case 255:
 						numData = math.NaN()
 					default:
@@ -2031,33 +2283,99 @@ func (r *Reader) readDataRecord() (Row, error) {
 					}
 				default:
 					// Add error handling here
-					return nil, io.EOF
+					return io.EOF
 				}
 				chunksToRead--
 			}
-			if Var.Numeric {
-				row = append(row, numData)
-			} else {
-				row = append(row, strings.TrimSpace(strData))
+			if skip {
+				continue
+			}
+			// Only the first segmentLen bytes of all but the last segment
+			// of a very long string are used.
+			if s < len(segments)-1 && len(segData) > segmentLen {
+				segData = segData[:segmentLen]
 			}
+			strData += segData
 		}
-		if len(row) == 0 {
-			return nil, io.EOF
+		if skip {
+			continue
+		}
+		if Var.Numeric {
+			sink.setNumber(col, numData)
+		} else {
+			sink.setString(col, strings.TrimSpace(r.decode(strData)))
 		}
-		return row, nil
 	}
+	r.caseNum++
+	return nil
+}
+
+// numericValue returns the value of a numeric variable as it is returned by
+// Read, converted according to the options of the Reader.
+func (r *Reader) numericValue(v *Variable, f float64) interface{} {
+	if value, ok := r.applyMissing(v, f, func() interface{} { return r.labelValue(v, f) }); ok {
+		if nan, ok := value.(float64); ok {
+			return r.dateValue(v, nan)
+		}
+		return value
+	}
+	return r.labelValue(v, f)
+}
+
+// labelValue returns the label of f if the Reader returns value labels and
+// v has a label for f, or else f as returned by dateValue.
+func (r *Reader) labelValue(v *Variable, f float64) interface{} {
+	if r.opts.labels {
+		if label, ok := indexedLabel(r.labels[v], f); ok {
+			return label
+		}
+	}
+	return r.dateValue(v, f)
+}
+
+// dateValue returns f as a Go type if the Reader returns date and time
+// values as Go types and v has a date or time format.
+func (r *Reader) dateValue(v *Variable, f float64) interface{} {
+	if r.opts.dates {
+		if value, ok := dateValue(v.Print.Type, f); ok {
+			return value
+		}
+	}
+	return f
+}
+
+// stringValue returns the value of a string variable as it is returned by
+// Read, converted according to the options of the Reader.
+func (r *Reader) stringValue(v *Variable, s string) interface{} {
+	converted := func() interface{} {
+		if r.opts.labels {
+			if label, ok := indexedLabel(r.labels[v], s); ok {
+				return label
+			}
+		}
+		return s
+	}
+	if value, ok := r.applyMissing(v, s, converted); ok {
+		return value
+	}
+	return converted()
 }
 
 // If data record is zlib compressed.
 type zLibDataHeader struct {
-	zHeaderOffset  int64
+	// Offset of the start of this header in the file.
+	zHeaderOffset int64
+
+	// Offset of the trailer in the file.
 	zTrailerOffset int64
+
+	// Length of the trailer in bytes, 24 plus 24 times the number of blocks.
 	zTrailerLength int64
 }
 
+// readZLibHeader returns a pointer to a zlibdataheader and an error.
 func (r *Reader) readZLibHeader() (*zLibDataHeader, error) {
 	m := new(zLibDataHeader)
-	r.r.Peek(2000)
 	m.zHeaderOffset, err = r.readInt64()
 	if err != nil {
 		return nil, err
@@ -2075,19 +2393,43 @@ func (r *Reader) readZLibHeader() (*zLibDataHeader, error) {
 
 // If data record is zlib compressed.
 type zLibDataTrailer struct {
-	bias               int64
-	zero               int64
-	blockSize          int32
-	nBlocks            int32
+	// The compression bias as a negative number, e.g. -100.
+	bias int64
+
+	// Always set to 0.
+	zero int64
+
+	// The number of bytes in each ZLIB compressed data block, except
+	// possibly the last, following decompression. Normally 0x3ff000.
+	blockSize int32
+
+	// The number of ZLIB compressed data blocks.
+	nBlocks int32
+
+	// List of zLibBlock structs, one for each block.
+	blocks []*zLibBlock
+}
+
+// Describes one ZLIB compressed block of data.
+type zLibBlock struct {
+	// The offset, in bytes, the block would have in the file if the data
+	// was only bytecode compressed. The first block starts at the offset of
+	// the zlib header.
 	unCompressedOffset int64
-	compressedOffset   int64
-	unCompressedSize   int32
-	compressedSize     int32
+
+	// The offset, in bytes, of the compressed block in the file.
+	compressedOffset int64
+
+	// The number of bytes in the block after decompression.
+	unCompressedSize int32
+
+	// The number of bytes in the compressed block.
+	compressedSize int32
 }
 
+// readZLibTrailer returns a pointer to a zlibdatatrailer and an error.
 func (r *Reader) readZLibTrailer() (*zLibDataTrailer, error) {
 	m := new(zLibDataTrailer)
-	r.r.Peek(2000)
 	m.bias, err = r.readInt64()
 	if err != nil {
 		return nil, err
@@ -2104,23 +2446,168 @@ func (r *Reader) readZLibTrailer() (*zLibDataTrailer, error) {
 	if err != nil {
 		return nil, err
 	}
-	m.unCompressedOffset, err = r.readInt64()
-	if err != nil {
-		return nil, err
+	for i := 0; i < int(m.nBlocks); i++ {
+		b := new(zLibBlock)
+		b.unCompressedOffset, err = r.readInt64()
+		if err != nil {
+			return nil, err
+		}
+		b.compressedOffset, err = r.readInt64()
+		if err != nil {
+			return nil, err
+		}
+		b.unCompressedSize, err = r.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		b.compressedSize, err = r.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		m.blocks = append(m.blocks, b)
 	}
-	m.compressedOffset, err = r.readInt64()
-	if err != nil {
-		return nil, err
+	return m, nil
+}
+
+// parseZLibTrailer returns the zlibdatatrailer encoded in b and an error.
+func (r *Reader) parseZLibTrailer(b []byte) (*zLibDataTrailer, error) {
+	tr := &Reader{
+		endianess: r.endianess,
+		r:         bufio.NewReader(bytes.NewReader(b)),
 	}
-	m.unCompressedSize, err = r.readInt32()
-	if err != nil {
-		return nil, err
+	return tr.readZLibTrailer()
+}
+
+// zlibReader streams the data of a zlib compressed file. Only the block
+// being read is decompressed, so memory use does not depend on file size.
+type zlibReader struct {
+	r      *Reader
+	header *Header
+	// pos is the offset of the next byte of decompressed data, as if the file
+	// was only bytecode compressed.
+	pos int64
+	// zr decompresses the current block, it is nil between blocks. dec is
+	// the decompressor, which is reset for every block.
+	zr  io.ReadCloser
+	dec io.ReadCloser
+	// block is the index of the next block in the trailer.
+	block int
+	// eof is set once all blocks are read.
+	eof bool
+}
+
+// Read reads decompressed data, moving on to the next block when the
+// current one is exhausted.
+func (z *zlibReader) Read(p []byte) (int, error) {
+	for {
+		if z.eof {
+			return 0, io.EOF
+		}
+		if z.zr == nil {
+			if err := z.next(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		n, err := z.zr.Read(p)
+		z.pos += int64(n)
+		if err == io.EOF {
+			z.zr = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
 	}
-	m.compressedSize, err = r.readInt32()
-	if err != nil {
-		return nil, err
+}
+
+// next starts decompressing the next block. Blocks are taken from the
+// trailer if it has been read, otherwise blocks follow each other until the
+// offset of the trailer, which is then read.
+func (z *zlibReader) next() error {
+	r := z.r
+	var src io.Reader = r.r
+	if trailer := z.header.ZLibDataTrailer; trailer != nil {
+		if z.block >= len(trailer.blocks) {
+			z.eof = true
+			return nil
+		}
+		b := trailer.blocks[z.block]
+		// The previous block may not have been read to its very end.
+		if skip := b.compressedOffset - r.offset(); skip > 0 {
+			if _, err := r.r.Discard(int(skip)); err != nil {
+				return err
+			}
+		}
+		src = io.LimitReader(r.r, int64(b.compressedSize))
+		z.pos = b.unCompressedOffset
+	} else if r.offset() >= z.header.ZLibDataHeader.zTrailerOffset {
+		b := make([]byte, z.header.ZLibDataHeader.zTrailerLength)
+		if _, err := io.ReadFull(r.r, b); err != nil {
+			return err
+		}
+		trailer, err := r.parseZLibTrailer(b)
+		if err != nil {
+			return err
+		}
+		z.header.ZLibDataTrailer = trailer
+		z.block = len(trailer.blocks)
+		z.eof = true
+		return nil
 	}
-	return m, nil
+	z.block++
+	if z.dec == nil {
+		dec, err := zlib.NewReader(src)
+		if err != nil {
+			return err
+		}
+		z.dec = dec
+	} else if err := z.dec.(zlib.Resetter).Reset(src, nil); err != nil {
+		return err
+	}
+	z.zr = z.dec
+	return nil
+}
+
+// layoutEndianess returns the byte order in which the layout code b, which
+// is 2 or 3, makes sense or nil if it makes sense in neither.
+func layoutEndianess(b []byte) binary.ByteOrder {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		if code := order.Uint32(b); code == 2 || code == 3 {
+			return order
+		}
+	}
+	return nil
+}
+
+// checkEndianess confirms the byte order with the endianness of the machine
+// integer record, unless the byte order is forced with WithByteOrder.
+func (r *Reader) checkEndianess(h *Header) error {
+	if r.opts.byteOrder != nil || h.MachineIntegerInfo == nil {
+		return nil
+	}
+	var order binary.ByteOrder
+	switch h.MachineIntegerInfo.endianess {
+	case 1:
+		order = binary.BigEndian
+	case 2:
+		order = binary.LittleEndian
+	default:
+		return nil
+	}
+	if order != r.endianess {
+		return ErrByteOrder
+	}
+	return nil
+}
+
+// otherEndianess returns the byte order that is not order.
+func otherEndianess(order binary.ByteOrder) binary.ByteOrder {
+	if order == binary.BigEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
 }
 
 // Checks the system default endianess to infer the most likely endianess.
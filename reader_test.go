@@ -1,11 +1,17 @@
 package gospss
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -57,6 +63,382 @@ func TestReaderAll(t *testing.T) {
 	}
 }
 
+func TestReaderZLibStream(t *testing.T) {
+	readAll := func(name string, stream bool) ([]Row, *Reader) {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("failed to open %s ::: err >>> %s", name, err)
+		}
+		defer f.Close()
+		var src io.Reader = f
+		if stream {
+			// Hide the Seek method so the trailer can not be read up front.
+			src = struct{ io.Reader }{f}
+		}
+		r, err := NewReader(src)
+		if err != nil {
+			t.Fatalf("failed to read %s ::: err >>> %s", name, err)
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read all records %s ::: err >>> %s", name, err)
+		}
+		return rows, r
+	}
+
+	sav, _ := readAll(TEST_FILE, false)
+	want, _ := readAll(TEST_FILE_GZIP, false)
+	if len(want) != len(sav) {
+		t.Errorf("got %d rows from %s, want %d", len(want), TEST_FILE_GZIP, len(sav))
+	}
+	got, r := readAll(TEST_FILE_GZIP, true)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rows of streamed %s differ from seeked", TEST_FILE_GZIP)
+	}
+	if r.header.ZLibDataTrailer == nil || r.header.ZLibDataTrailer.nBlocks != 1 {
+		t.Errorf("trailer of streamed %s not read", TEST_FILE_GZIP)
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	d := r.Dictionary()
+	if d.NumCases != 3742 || d.Compression != Bytecode || d.Weight != nil || len(d.Variables) != 84 {
+		t.Errorf("unexpected dictionary %+v", d)
+	}
+	if d.Product != "IBM SPSS STATISTICS 64-bit MS Windows 22.0.0.0" {
+		t.Errorf("got product %q", d.Product)
+	}
+	if want := time.Date(2016, time.November, 28, 18, 18, 41, 0, time.UTC); !d.Created.Equal(want) {
+		t.Errorf("got created %s, want %s", d.Created, want)
+	}
+	if d.Variables[0].Name != "RespondentID" || d.Variables[0].Role != RoleInput {
+		t.Errorf("unexpected variable %+v", d.Variables[0])
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	// A string of 8 bytes printed as AHEX16.
+	f := new(savFile)
+	f.header(1, 1)
+	format := int32(2<<16 | 16<<8)
+	f.int32s(2, 8, 0, 0, format, format)
+	f.padded("HEX", 8)
+	f.machineInteger(65001)
+	f.machineFloatingPoint()
+	f.end()
+	f.padded("abcdefgh", 8)
+
+	r, err := NewReader(bytes.NewReader(f.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	d := r.Dictionary()
+	if v := d.Variables[0]; v.Width != 8 || v.Print.String() != "AHEX16" {
+		t.Fatalf("got width %d and print format %s, want 8 and AHEX16", v.Width, v.Print)
+	}
+
+	// The dictionary writes a file with the same width.
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]Row{{"abcdefgh"}}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	r, err = NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if v := r.Dictionary().Variables[0]; v.Width != 8 || v.Print.String() != "AHEX16" {
+		t.Errorf("got width %d and print format %s, want 8 and AHEX16", v.Width, v.Print)
+	}
+}
+
+func TestVeryLongStrings(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	v := r.Dictionary().Variables[82]
+	if v.Name != "OpenEnd1" || v.Width != 500 || v.Print.String() != "A500" || len(v.segments) != 2 {
+		t.Errorf("unexpected very long string %+v", v)
+	}
+
+	long := strings.Repeat("0123456789", 60)
+	d := &Dictionary{
+		Variables: []*Variable{
+			{Name: "Comment", Label: "Open-ended comment", Width: 600},
+			{Name: "Score", Numeric: true},
+			{Name: "Note", Width: 300},
+		},
+	}
+	rows := []Row{
+		{long, 1.0, long[:300]},
+		{long[:252] + "x", 2.0, ""},
+		{"", 3.0, long[:253]},
+	}
+	for _, compression := range []Compression{Uncompressed, Bytecode, ZLib} {
+		d.Compression = compression
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, d)
+		if err != nil {
+			t.Fatalf("compression %d: failed to create writer ::: err >>> %s", compression, err)
+		}
+		if err := w.WriteAll(rows); err != nil {
+			t.Fatalf("compression %d: failed to write rows ::: err >>> %s", compression, err)
+		}
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("compression %d: failed to read ::: err >>> %s", compression, err)
+		}
+		variables := r.Dictionary().Variables
+		if len(variables) != 3 || variables[0].Width != 600 || variables[0].Label != "Open-ended comment" || variables[2].Width != 300 {
+			t.Fatalf("compression %d: unexpected variables %+v", compression, variables)
+		}
+		got, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("compression %d: failed to read all records ::: err >>> %s", compression, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(rows) {
+			t.Errorf("compression %d: got %v, want %v", compression, got, rows)
+		}
+	}
+}
+
+func TestVariableDisplay(t *testing.T) {
+	f, err := os.Open(TEST_FILE)
+	if err != nil {
+		t.Fatalf("failed to open %s ::: err >>> %s", TEST_FILE, err)
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read %s ::: err >>> %s", TEST_FILE, err)
+	}
+	variables := r.Dictionary().Variables
+	for i, want := range map[int]string{
+		0:  "Unknown 8 Right",
+		1:  "Scale 8 Right",
+		5:  "Nominal 8 Right",
+		82: "Nominal 33 Left",
+		83: "Nominal 25 Left",
+	} {
+		v := variables[i]
+		if got := fmt.Sprint(v.Measure, " ", v.DisplayWidth, " ", v.Alignment); got != want {
+			t.Errorf("variable %s: got %s, want %s", v.Name, got, want)
+		}
+	}
+
+	// Older files have only the measure and the alignment.
+	b := new(bytes.Buffer)
+	for _, i := range []int32{7, 11, 4, 4, 2, 1, 3, 0} {
+		binary.Write(b, binary.LittleEndian, i)
+	}
+	dr := &Reader{endianess: binary.LittleEndian, r: bufio.NewReader(b)}
+	display, err := dr.readVariableDisplay([]*variabler{{tpe: 0}, {tpe: 8}, {tpe: -1}})
+	if err != nil {
+		t.Fatalf("failed to read display parameters ::: err >>> %s", err)
+	}
+	if len(display.display) != 2 || display.display[0].measure != 2 || display.display[0].alignment != 1 ||
+		display.display[1].measure != 3 || display.display[1].width != 0 {
+		t.Errorf("unexpected display parameters %+v %+v", display.display[0], display.display[1])
+	}
+}
+
+func TestRawRecords(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.RawRecords = []*RawRecord{
+		{Subtype: 5, Size: 1, Count: 23, Data: []byte("Ids= RespondentID Score")},
+		{Subtype: 24, Size: 1, Count: 12, Data: []byte("<xml></xml>\n")},
+		{Subtype: 12, Size: 4, Count: 2, Data: []byte{1, 0, 0, 0, 2, 0, 0, 0}},
+		{Subtype: 13, Size: 1, Count: 3, Data: []byte("A=B")},
+	}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d, WithByteOrder(binary.LittleEndian))
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll(testRows); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	raw := r.Dictionary().RawRecords
+	if len(raw) != 3 {
+		t.Fatalf("got %d raw records, want 3", len(raw))
+	}
+	for i, want := range d.RawRecords[:3] {
+		if raw[i].Subtype != want.Subtype || raw[i].Size != want.Size || raw[i].Count != int32(len(want.Data))/want.Size ||
+			!bytes.Equal(raw[i].Data, want.Data) {
+			t.Errorf("got raw record %+v, want %+v", raw[i], want)
+		}
+	}
+	if r.Dictionary().Variables[1].Name != "Score" {
+		t.Errorf("got variable %s, want Score", r.Dictionary().Variables[1].Name)
+	}
+	rows, err := r.ReadAll()
+	if err != nil || len(rows) != len(testRows) {
+		t.Errorf("got %d rows and error %v, want %d", len(rows), err, len(testRows))
+	}
+
+	// A record that is not an extension record can not be skipped.
+	b := buf.Bytes()
+	i := bytes.Index(b, []byte("Ids= "))
+	binary.LittleEndian.PutUint32(b[i-16:], 8)
+	if _, err := NewReader(bytes.NewReader(b), WithByteOrder(binary.LittleEndian)); err != ErrUnknownRecord {
+		t.Errorf("got error %v, want %v", err, ErrUnknownRecord)
+	}
+}
+
+func TestRawRecordsByteOrder(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.RawRecords = []*RawRecord{
+		{Subtype: 12, Size: 4, Data: []byte{0, 0, 0, 1, 0, 0, 0, 2}},
+		{Subtype: 5, Size: 1, Data: []byte("Ids= RespondentID")},
+	}
+	var file []byte
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, d, WithByteOrder(order))
+		if err != nil {
+			t.Fatalf("%s: failed to create writer ::: err >>> %s", order, err)
+		}
+		if err := w.WriteAll(testRows); err != nil {
+			t.Fatalf("%s: failed to write rows ::: err >>> %s", order, err)
+		}
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to read ::: err >>> %s", order, err)
+		}
+		// The records of the big-endian file are written to a little-endian
+		// one next.
+		d = r.Dictionary()
+		file = buf.Bytes()
+	}
+	raw := d.RawRecords
+	if len(raw) != 2 || raw[0].ByteOrder != binary.LittleEndian || !bytes.Equal(raw[0].Data, []byte{1, 0, 0, 0, 2, 0, 0, 0}) ||
+		string(raw[1].Data) != "Ids= RespondentID" {
+		t.Errorf("got raw records %+v %+v", raw[0], raw[1])
+	}
+	if !bytes.Contains(file, []byte{7, 0, 0, 0, 12, 0, 0, 0, 4, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0}) {
+		t.Errorf("file does not contain the little-endian record")
+	}
+}
+
+// savFile lays out the records of a little-endian system file byte by byte
+// as described in the PSPP documentation of the format, so that tests of
+// the Reader do not depend on the Writer.
+type savFile struct {
+	bytes.Buffer
+}
+
+// int32s writes values as 4-byte integers.
+func (f *savFile) int32s(values ...int32) {
+	binary.Write(f, binary.LittleEndian, values)
+}
+
+// flt64s writes values as 8-byte floating point numbers.
+func (f *savFile) flt64s(values ...float64) {
+	binary.Write(f, binary.LittleEndian, values)
+}
+
+// padded writes s padded with spaces to n bytes.
+func (f *savFile) padded(s string, n int) {
+	f.WriteString(s + strings.Repeat(" ", n-len(s)))
+}
+
+// header writes the file header of ncases uncompressed cases of n 8-byte
+// elements each.
+func (f *savFile) header(n, ncases int32) {
+	f.WriteString("$FL2")
+	f.padded("@(#) SPSS DATA FILE test records", 60)
+	f.int32s(2, n, 0, 0, ncases)
+	f.flt64s(100)
+	f.WriteString("01 Jan 2600:00:00")
+	f.padded("", 64)
+	f.Write([]byte{0, 0, 0})
+}
+
+// variable writes the variable records of a variable of width, 0 for a
+// numeric one, with the label unless it is empty, followed by the
+// continuation records of a string wider than 8 bytes.
+func (f *savFile) variable(name string, width int32, label string) {
+	format := int32(5<<16 | 8<<8)
+	if width > 0 {
+		format = 1<<16 | width<<8
+	}
+	var hasLabel int32
+	if label != "" {
+		hasLabel = 1
+	}
+	f.int32s(2, width, hasLabel, 0, format, format)
+	f.padded(name, 8)
+	if label != "" {
+		f.int32s(int32(len(label)))
+		f.padded(label, (len(label)+3)/4*4)
+	}
+	for i := int32(8); i < width; i += 8 {
+		f.int32s(2, -1, 0, 0, 0, 0)
+		f.padded("", 8)
+	}
+}
+
+// valueLabels writes the value label record of the 8-byte values and their
+// labels, followed by the record of the variable at index, counting from 1,
+// they belong to.
+func (f *savFile) valueLabels(index int32, labels ...[2]string) {
+	f.int32s(3, int32(len(labels)))
+	for _, vl := range labels {
+		f.padded(vl[0], 8)
+		f.WriteByte(byte(len(vl[1])))
+		f.padded(vl[1], (len(vl[1])+8)/8*8-1)
+	}
+	f.int32s(4, 1, index)
+}
+
+// extension writes the extension record of subtype with data, of count
+// elements of size bytes.
+func (f *savFile) extension(subtype, size int32, data []byte) {
+	f.int32s(7, subtype, size, int32(len(data))/size)
+	f.Write(data)
+}
+
+// machineInteger writes the machine integer record of a little-endian file
+// with the character code.
+func (f *savFile) machineInteger(characterCode int32) {
+	f.int32s(7, 3, 4, 8, 24, 0, 0, -1, 1, 1, 2, characterCode)
+}
+
+// machineFloatingPoint writes the machine floating point record of the
+// values IBM SPSS Statistics uses.
+func (f *savFile) machineFloatingPoint() {
+	f.int32s(7, 4, 8, 3)
+	f.flt64s(-math.MaxFloat64, math.MaxFloat64, math.Nextafter(-math.MaxFloat64, 0))
+}
+
+// end writes the dictionary termination record.
+func (f *savFile) end() {
+	f.int32s(999, 0)
+}
+
 // TODO: more serious unit testing for each function...
 // var readTests = []struct {
 // 	Name   string
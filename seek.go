@@ -0,0 +1,185 @@
+package gospss
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotSeekable   = errors.New("Data file can not be seeked.")
+	ErrNegativeCase  = errors.New("Case number can not be negative.")
+	ErrCaseInterval  = errors.New("Case index interval must be positive.")
+	ErrNotInZLibData = errors.New("Position is outside of the zlib compressed data.")
+)
+
+// NewReaderAt returns a new Reader that reads the size bytes of the data
+// file from r. Unlike a Reader returned by NewReader on a plain io.Reader,
+// it can always seek to any case with SeekCase.
+func NewReaderAt(r io.ReaderAt, size int64, opts ...Option) (*Reader, error) {
+	return NewReader(io.NewSectionReader(r, 0, size), opts...)
+}
+
+// caseIndexEntry is where a case starts in a compressed file. Since a block
+// of opcodes can span several cases the state of the current block is kept
+// too.
+type caseIndexEntry struct {
+	// pos is the position of the case, see dataPos.
+	pos         int64
+	opcodes     []byte
+	opcodeIndex int
+}
+
+// dataPos returns the position of the next unread byte of data. For zlib
+// compressed files this is the offset in the decompressed data, counting as
+// if the file was only bytecode compressed.
+func (r *Reader) dataPos() int64 {
+	if r.zlib {
+		return r.z.pos
+	}
+	return r.offset()
+}
+
+// seekData positions r at pos, as returned by dataPos, with a fresh block of
+// opcodes.
+func (r *Reader) seekData(pos int64) error {
+	if r.seeker == nil {
+		return ErrNotSeekable
+	}
+	r.opcodes = nil
+	r.opcodeIndex = 8
+	if r.zlib {
+		return r.z.seek(pos)
+	}
+	return r.seek(pos)
+}
+
+// seek positions z at pos in the decompressed data. Only the block holding
+// pos is decompressed.
+func (z *zlibReader) seek(pos int64) error {
+	trailer := z.header.ZLibDataTrailer
+	for i, b := range trailer.blocks {
+		if pos < b.unCompressedOffset || pos > b.unCompressedOffset+int64(b.unCompressedSize) {
+			continue
+		}
+		if err := z.r.seek(b.compressedOffset); err != nil {
+			return err
+		}
+		z.block = i
+		z.zr = nil
+		z.eof = false
+		if err := z.next(); err != nil {
+			return err
+		}
+		_, err := io.CopyN(io.Discard, z, pos-b.unCompressedOffset)
+		return err
+	}
+	if len(trailer.blocks) == 0 && pos == z.header.ZLibDataHeader.zHeaderOffset {
+		z.eof = true
+		return nil
+	}
+	return ErrNotInZLibData
+}
+
+// SeekCase positions r so that the next call to Read returns case n,
+// counting from 0.
+//
+// For uncompressed files the position of the case is calculated. Cases of
+// compressed files differ in size, so they have to be read through from the
+// nearest known position: the current case, a case in the index built by
+// BuildCaseIndex or else the first case. For zlib compressed files only the
+// blocks that are read through are decompressed.
+//
+// Without an index, SeekCase on a compressed file therefore takes time
+// proportional to the number of cases read through, so seeking back to case
+// n of a .zsav file decompresses every block up to case n. The trailer of a
+// .zsav file does not help, as it tells how many bytes a block holds but not
+// how many cases. Call BuildCaseIndex first when seeking more than once.
+//
+// Without an index, only seeking forward works unless the Reader was
+// returned by NewReaderAt or NewReader was given an io.Seeker.
+func (r *Reader) SeekCase(n int64) error {
+	if n < 0 {
+		return ErrNegativeCase
+	}
+	if r.header.Fileheader.compression == 0 && r.seeker != nil {
+		caseSize := int64(r.header.Fileheader.nominalCaseSize) * 8
+		if err := r.seekData(r.dataStart + n*caseSize); err != nil {
+			return err
+		}
+		r.caseNum = n
+		return nil
+	}
+
+	// Find the closest known case before n.
+	if len(r.index) > 0 {
+		i := n / r.indexInterval
+		if i >= int64(len(r.index)) {
+			i = int64(len(r.index)) - 1
+		}
+		if c := i * r.indexInterval; n < r.caseNum || c > r.caseNum {
+			e := r.index[i]
+			if err := r.seekData(e.pos); err != nil {
+				return err
+			}
+			r.opcodes = append([]byte(nil), e.opcodes...)
+			r.opcodeIndex = e.opcodeIndex
+			r.caseNum = c
+		}
+	}
+	if n < r.caseNum {
+		if err := r.seekData(r.dataStart); err != nil {
+			return err
+		}
+		r.caseNum = 0
+	}
+	for r.caseNum < n {
+		if _, err := r.readCase(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildCaseIndex reads through every case of a compressed file and
+// remembers where every interval'th case starts, so that SeekCase only has
+// to read through at most interval cases. Afterwards r is positioned at the
+// case it was at before. It does nothing for uncompressed files, where the
+// position of each case is known.
+//
+// The Reader must have been returned by NewReaderAt or NewReader must have
+// been given an io.Seeker.
+func (r *Reader) BuildCaseIndex(interval int64) error {
+	if interval <= 0 {
+		return ErrCaseInterval
+	}
+	if r.header.Fileheader.compression == 0 {
+		return nil
+	}
+	if r.seeker == nil {
+		return ErrNotSeekable
+	}
+	current := r.caseNum
+	if err := r.seekData(r.dataStart); err != nil {
+		return err
+	}
+	r.caseNum = 0
+	r.index = nil
+	r.indexInterval = interval
+	for {
+		if r.caseNum%interval == 0 {
+			r.index = append(r.index, &caseIndexEntry{
+				pos:         r.dataPos(),
+				opcodes:     append([]byte(nil), r.opcodes...),
+				opcodeIndex: r.opcodeIndex,
+			})
+		}
+		_, err := r.readCase()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return r.SeekCase(current)
+}
@@ -0,0 +1,83 @@
+package gospss
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSeekCase(t *testing.T) {
+	files := map[string]func() (*Reader, error){}
+	for _, name := range []string{TEST_FILE, TEST_FILE_GZIP} {
+		name := name
+		files[name] = func() (*Reader, error) {
+			f, err := os.Open(name)
+			if err != nil {
+				return nil, err
+			}
+			t.Cleanup(func() { f.Close() })
+			fi, err := f.Stat()
+			if err != nil {
+				return nil, err
+			}
+			return NewReaderAt(f, fi.Size())
+		}
+	}
+	for _, compression := range []Compression{Uncompressed, ZLib} {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, testDictionary(compression))
+		if err != nil {
+			t.Fatalf("failed to create writer ::: err >>> %s", err)
+		}
+		if compression == ZLib {
+			w.z.blockSize = 64
+		}
+		for i := 0; i < 200; i++ {
+			if err := w.Write(Row{float64(i), 0.5 * float64(i), fmt.Sprint("case ", i), "A"}); err != nil {
+				t.Fatalf("failed to write row ::: err >>> %s", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close writer ::: err >>> %s", err)
+		}
+		files[fmt.Sprint("written with compression ", compression)] = func() (*Reader, error) {
+			return NewReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		}
+	}
+
+	for name, open := range files {
+		r, err := open()
+		if err != nil {
+			t.Fatalf("%s: failed to read ::: err >>> %s", name, err)
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: failed to read all records ::: err >>> %s", name, err)
+		}
+		check := func(n int) {
+			if err := r.SeekCase(int64(n)); err != nil {
+				t.Fatalf("%s: failed to seek to case %d ::: err >>> %s", name, n, err)
+			}
+			row, err := r.Read()
+			if err != nil {
+				t.Fatalf("%s: failed to read case %d ::: err >>> %s", name, n, err)
+			}
+			if fmt.Sprint(row) != fmt.Sprint(rows[n]) {
+				t.Errorf("%s: case %d got %v, want %v", name, n, row, rows[n])
+			}
+		}
+		last := len(rows) - 1
+		check(last)
+		check(5)
+		check(0)
+		check(last / 2)
+		if err := r.BuildCaseIndex(7); err != nil {
+			t.Fatalf("%s: failed to build index ::: err >>> %s", name, err)
+		}
+		check(last)
+		check(last/3 + 1)
+		check(22)
+		check(21)
+	}
+}
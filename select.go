@@ -0,0 +1,80 @@
+package gospss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrDuplicateVariable = errors.New("Variable is selected more than once.")
+
+// Select makes the Reader return only the variables with the given names,
+// matched case insensitively against the long and the short variable names,
+// in the order given. The values of the other variables are skipped while
+// reading without being converted. Calling Select without names selects all
+// variables again. Select can be called between cases, and applies to Read,
+// ReadAll and ReadInto alike.
+func (r *Reader) Select(names ...string) error {
+	if len(names) == 0 {
+		r.selection, r.columns, r.fields = nil, nil, nil
+		return nil
+	}
+	selection := make([]*Variable, 0, len(names))
+	columns := make([]int, len(r.header.metaData))
+	for i := range columns {
+		columns[i] = -1
+	}
+	for col, name := range names {
+		i := r.variableIndex(name)
+		if i < 0 {
+			return fmt.Errorf("%s: %w", name, ErrUnknownVariable)
+		}
+		if columns[i] >= 0 {
+			return fmt.Errorf("%s: %w", name, ErrDuplicateVariable)
+		}
+		columns[i] = col
+		selection = append(selection, r.header.metaData[i])
+	}
+	r.selection, r.columns, r.fields = selection, columns, nil
+	return nil
+}
+
+// Selected returns the variables of the rows returned by the Reader, in the
+// order of the values in a row, as set by Select.
+func (r *Reader) Selected() []*Variable {
+	if r.selection == nil {
+		return r.header.metaData
+	}
+	return r.selection
+}
+
+// variableIndex returns the index of the variable with the long or short
+// name name, or -1 if there is none.
+func (r *Reader) variableIndex(name string) int {
+	for i, v := range r.header.metaData {
+		if strings.EqualFold(v.Name, name) || strings.EqualFold(v.id, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// column returns the index in a row of the value of the i'th variable, or
+// -1 if the variable is not selected.
+func (r *Reader) column(i int) int {
+	if r.columns == nil {
+		return i
+	}
+	return r.columns[i]
+}
+
+// skipBytes discards the next n bytes of data.
+func (r *Reader) skipBytes(n int) error {
+	if r.zlib {
+		_, err := io.CopyN(io.Discard, r.z, int64(n))
+		return err
+	}
+	_, err := r.r.Discard(n)
+	return err
+}
@@ -0,0 +1,121 @@
+package gospss
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	for _, compression := range []Compression{Uncompressed, Bytecode, ZLib} {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, testDictionary(compression))
+		if err != nil {
+			t.Fatalf("compression %d: failed to create writer ::: err >>> %s", compression, err)
+		}
+		if err := w.WriteAll(testRows); err != nil {
+			t.Fatalf("compression %d: failed to write rows ::: err >>> %s", compression, err)
+		}
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()), WithValueLabels())
+		if err != nil {
+			t.Fatalf("compression %d: failed to read ::: err >>> %s", compression, err)
+		}
+		if err := r.Select("code", "Score", "RespondentID"); err != nil {
+			t.Fatalf("compression %d: failed to select ::: err >>> %s", compression, err)
+		}
+		if s := r.Selected(); len(s) != 3 || s[0].Name != "Code" || s[2].Name != "RespondentID" {
+			t.Errorf("compression %d: got selected %v", compression, s)
+		}
+		row, err := r.Read()
+		if err != nil {
+			t.Fatalf("compression %d: failed to read row ::: err >>> %s", compression, err)
+		}
+		if got, want := fmt.Sprint(row), "[A Low 1]"; got != want {
+			t.Errorf("compression %d: got %s, want %s", compression, got, want)
+		}
+
+		// Selecting all variables again in the middle of the file.
+		if err := r.Select(); err != nil {
+			t.Fatalf("compression %d: failed to select ::: err >>> %s", compression, err)
+		}
+		row, err = r.Read()
+		if err != nil {
+			t.Fatalf("compression %d: failed to read row ::: err >>> %s", compression, err)
+		}
+		if got, want := fmt.Sprint(row), "[2 2.5 Rio de Janeiro city ]"; got != want {
+			t.Errorf("compression %d: got %s, want %s", compression, got, want)
+		}
+
+		if err := r.Select("City"); err != nil {
+			t.Fatalf("compression %d: failed to select ::: err >>> %s", compression, err)
+		}
+		var cities []struct{ City string }
+		if err := r.ReadAllInto(&cities); err != nil {
+			t.Fatalf("compression %d: failed to read all cases ::: err >>> %s", compression, err)
+		}
+		if got, want := fmt.Sprint(cities), "[{} {Oslo}]"; got != want {
+			t.Errorf("compression %d: got %s, want %s", compression, got, want)
+		}
+
+		if err := r.Select("City", "Age"); !errors.Is(err, ErrUnknownVariable) {
+			t.Errorf("compression %d: got error %v, want %v", compression, err, ErrUnknownVariable)
+		}
+		if err := r.Select("City", "city"); !errors.Is(err, ErrDuplicateVariable) {
+			t.Errorf("compression %d: got error %v, want %v", compression, err, ErrDuplicateVariable)
+		}
+	}
+}
+
+func TestSelectVeryLongString(t *testing.T) {
+	for _, file := range []string{TEST_FILE, TEST_FILE_GZIP} {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("failed to open %s ::: err >>> %s", file, err)
+		}
+		defer f.Close()
+		r, err := NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read %s ::: err >>> %s", file, err)
+		}
+		all, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read all records of %s ::: err >>> %s", file, err)
+		}
+
+		var names []string
+		var columns []int
+		for i, v := range r.MetaData() {
+			if i%7 == 3 || v.Name == "OPENEND1" {
+				names = append([]string{v.Name}, names...)
+				columns = append([]int{i}, columns...)
+			}
+		}
+		if _, err := f.Seek(0, 0); err != nil {
+			t.Fatalf("failed to seek %s ::: err >>> %s", file, err)
+		}
+		r, err = NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read %s ::: err >>> %s", file, err)
+		}
+		if err := r.Select(names...); err != nil {
+			t.Fatalf("failed to select ::: err >>> %s", err)
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read all records of %s ::: err >>> %s", file, err)
+		}
+		if len(rows) != len(all) {
+			t.Fatalf("%s: got %d rows, want %d", file, len(rows), len(all))
+		}
+		for n, row := range rows {
+			for col, i := range columns {
+				if got, want := fmt.Sprint(row[col]), fmt.Sprint(all[n][i]); got != want {
+					t.Fatalf("%s: row %d, variable %s: got %s, want %s", file, n, names[col], got, want)
+				}
+			}
+		}
+	}
+}
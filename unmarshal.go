@@ -0,0 +1,250 @@
+package gospss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotStructPointer = errors.New("Destination is not a pointer to a struct.")
+	ErrNotSlicePointer  = errors.New("Destination is not a pointer to a slice of structs.")
+	ErrUnknownVariable  = errors.New("No variable with the name of the field.")
+	ErrFieldType        = errors.New("Value can not be stored in the field.")
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// structField maps a struct field on to a variable.
+type structField struct {
+	// index is the index of the field in the struct.
+	index int
+	// v is the variable of the field and n the index of its value in a row.
+	v *Variable
+	n int
+	// label is set if the field receives the value label instead of the
+	// value, and labels indexes the labels of the variable.
+	label  bool
+	labels map[interface{}]string
+}
+
+// ReadInto reads the next case in to the struct dst points to. It returns
+// io.EOF when there are no more cases.
+//
+// Each exported field receives the value of the variable named by its spss
+// tag, or else by the name of the field, matched case insensitively against
+// the long and the short names of the variables selected with Select. A
+// field tagged "-" is skipped. A tag with the option "label", as in
+// `spss:"gender,label"`, makes a string field receive the value label
+// instead of the value, or the value as text if it has no label.
+//
+// Numeric values can be stored in fields of any integer, float or bool type,
+// in a string field as text, and in a time.Time or time.Duration field as a
+// date or time. The options of the Reader do not apply. Missing values, both
+// system- and user-missing, leave a pointer field, such as *int, nil, a
+// float field NaN and other fields at their zero value.
+func (r *Reader) ReadInto(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+	fields, err := r.structFields(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	row, err := r.readCase()
+	if err != nil {
+		return err
+	}
+	r.reportProgress()
+	return r.unmarshal(rv.Elem(), fields, row)
+}
+
+// ReadAllInto reads all remaining cases in to the slice dst points to, which
+// must be a slice of structs or of pointers to structs. The cases are
+// appended to the slice. See ReadInto for how the fields are set.
+func (r *Reader) ReadAllInto(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return ErrNotSlicePointer
+	}
+	slice := rv.Elem()
+	elem := slice.Type().Elem()
+	pointers := elem.Kind() == reflect.Pointer
+	if pointers {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return ErrNotSlicePointer
+	}
+	fields, err := r.structFields(elem)
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := r.readCase()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.reportProgress()
+		v := reflect.New(elem)
+		if err := r.unmarshal(v.Elem(), fields, row); err != nil {
+			return err
+		}
+		if pointers {
+			slice.Set(reflect.Append(slice, v))
+		} else {
+			slice.Set(reflect.Append(slice, v.Elem()))
+		}
+	}
+}
+
+// structFields returns the mapping of the fields of the struct type t on to
+// the variables.
+func (r *Reader) structFields(t reflect.Type) ([]*structField, error) {
+	if fields, ok := r.fields[t]; ok {
+		return fields, nil
+	}
+	var fields []*structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("spss")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		sf := &structField{index: i, n: -1, label: opts == "label"}
+		for n, v := range r.Selected() {
+			if strings.EqualFold(v.Name, name) || strings.EqualFold(v.id, name) {
+				sf.v, sf.n = v, n
+				sf.labels = r.labels[v]
+				break
+			}
+		}
+		if sf.v == nil {
+			if tag == "" {
+				// Only fields that name a variable have to have one.
+				continue
+			}
+			return nil, fmt.Errorf("field %s: %w", f.Name, ErrUnknownVariable)
+		}
+		fields = append(fields, sf)
+	}
+	if r.fields == nil {
+		r.fields = make(map[reflect.Type][]*structField)
+	}
+	r.fields[t] = fields
+	return fields, nil
+}
+
+// unmarshal sets the fields of the struct value dst from the values of a
+// case as returned by readCase.
+func (r *Reader) unmarshal(dst reflect.Value, fields []*structField, row Row) error {
+	for _, sf := range fields {
+		if err := setField(dst.Field(sf.index), sf, row[sf.n]); err != nil {
+			return fmt.Errorf("variable %s: %w", sf.v.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField stores the value of a variable in a field.
+func setField(field reflect.Value, sf *structField, value interface{}) error {
+	f, numeric := value.(float64)
+	missing := numeric && math.IsNaN(f) || sf.v.Missing.IsMissing(value)
+	if field.Kind() == reflect.Pointer {
+		if missing {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		p := reflect.New(field.Type().Elem())
+		if err := setField(p.Elem(), sf, value); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+	if missing {
+		field.Set(reflect.Zero(field.Type()))
+		if k := field.Kind(); k == reflect.Float32 || k == reflect.Float64 {
+			field.SetFloat(math.NaN())
+		}
+		return nil
+	}
+
+	if sf.label {
+		if label, ok := indexedLabel(sf.labels, value); ok {
+			value, numeric = label, false
+		}
+	}
+	switch field.Type() {
+	case timeType:
+		if !numeric {
+			return ErrFieldType
+		}
+		field.Set(reflect.ValueOf(TimeFromSPSS(f)))
+		return nil
+	case durationType:
+		if !numeric {
+			return ErrFieldType
+		}
+		field.SetInt(int64(DurationFromSPSS(f)))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		if numeric {
+			field.SetString(strconv.FormatFloat(f, 'f', -1, 64))
+		} else {
+			field.SetString(value.(string))
+		}
+	case reflect.Float32, reflect.Float64:
+		if !numeric {
+			return ErrFieldType
+		}
+		field.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// The float is checked against the bounds of the kind before it is
+		// converted, as converting a float out of range gives any value.
+		limit := math.Ldexp(1, field.Type().Bits()-1)
+		if !numeric || f != math.Trunc(f) || f < -limit || f >= limit {
+			return ErrFieldType
+		}
+		field.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !numeric || f < 0 || f != math.Trunc(f) || f >= math.Ldexp(1, field.Type().Bits()) {
+			return ErrFieldType
+		}
+		field.SetUint(uint64(f))
+	case reflect.Bool:
+		if !numeric {
+			return ErrFieldType
+		}
+		field.SetBool(f != 0)
+	case reflect.Interface:
+		if !reflect.TypeOf(value).AssignableTo(field.Type()) {
+			return ErrFieldType
+		}
+		field.Set(reflect.ValueOf(value))
+	default:
+		return ErrFieldType
+	}
+	return nil
+}
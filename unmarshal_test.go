@@ -0,0 +1,162 @@
+package gospss
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testCase struct {
+	ID         int      `spss:"RespondentID"`
+	Score      *float64 `spss:"score"`
+	ScoreLabel string   `spss:"Score,label"`
+	City       string
+	Code       string `spss:"CODE"`
+	Skipped    string `spss:"-"`
+	NotThere   int
+	unexported int
+}
+
+func TestReadInto(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, testDictionary(Bytecode))
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll(testRows); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+
+	// The options of the Reader do not change what the fields receive.
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), WithValueLabels())
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	var first testCase
+	if err := r.ReadInto(&first); err != nil {
+		t.Fatalf("failed to read case ::: err >>> %s", err)
+	}
+	if first.ID != 1 || first.Score == nil || *first.Score != 1 || first.ScoreLabel != "Low" || first.City != "Stockholm" || first.Code != "A" {
+		t.Errorf("got %+v", first)
+	}
+	var rest []*testCase
+	if err := r.ReadAllInto(&rest); err != nil {
+		t.Fatalf("failed to read all cases ::: err >>> %s", err)
+	}
+	var got []string
+	for _, c := range rest {
+		score := "nil"
+		if c.Score != nil {
+			score = fmt.Sprint(*c.Score)
+		}
+		got = append(got, fmt.Sprintf("%d %s %q %q %q", c.ID, score, c.ScoreLabel, c.City, c.Code))
+	}
+	want := `[2 2.5 "2.5" "Rio de Janeiro city" "" 3 nil "" "" "BCDE" -99 1.234567891e+06 "1234567.891" "Oslo" "F"]`
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if err := r.ReadInto(&first); err != io.EOF {
+		t.Errorf("got error %v, want %v", err, io.EOF)
+	}
+
+	r, err = NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if err := r.ReadInto(first); err != ErrNotStructPointer {
+		t.Errorf("got error %v, want %v", err, ErrNotStructPointer)
+	}
+	if err := r.ReadAllInto(&[]int{}); err != ErrNotSlicePointer {
+		t.Errorf("got error %v, want %v", err, ErrNotSlicePointer)
+	}
+	var unknown struct {
+		Age int `spss:"age"`
+	}
+	if err := r.ReadInto(&unknown); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownVariable)
+	}
+	var cases []struct {
+		Score int
+	}
+	if err := r.ReadAllInto(&cases); !errors.Is(err, ErrFieldType) {
+		t.Errorf("got error %v, want %v", err, ErrFieldType)
+	}
+}
+
+func TestReadIntoDates(t *testing.T) {
+	when := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	d := &Dictionary{
+		Compression: Bytecode,
+		Variables: []*Variable{
+			{Name: "When", Numeric: true, Print: Format{Type: FormatDATETIME, Width: 20}},
+			{Name: "Took", Numeric: true, Print: Format{Type: FormatTIME, Width: 8}},
+			{Name: "Missing", Numeric: true, Print: Format{Type: FormatDATE, Width: 11}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]Row{{when, 90 * time.Minute, nil}}); err != nil {
+		t.Fatalf("failed to write ::: err >>> %s", err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	var c struct {
+		When    time.Time
+		Took    time.Duration
+		Missing *time.Time
+	}
+	if err := r.ReadInto(&c); err != nil {
+		t.Fatalf("failed to read case ::: err >>> %s", err)
+	}
+	if !c.When.Equal(when) || c.Took != 90*time.Minute || c.Missing != nil {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestSetFieldRange(t *testing.T) {
+	var dst struct {
+		I   int
+		I8  int8
+		I64 int64
+		U8  uint8
+		U64 uint64
+	}
+	sf := &structField{v: &Variable{Name: "Value", Numeric: true}}
+	tests := []struct {
+		field string
+		value float64
+		ok    bool
+	}{
+		{"I8", 127, true},
+		{"I8", -128, true},
+		{"I8", 128, false},
+		{"I8", -129, false},
+		{"U8", 255, true},
+		{"U8", 256, false},
+		{"I64", -(1 << 63), true},
+		{"I64", 1 << 63, false},
+		{"I64", 1e30, false},
+		{"I64", -1e30, false},
+		{"I", math.Inf(1), false},
+		{"U64", 1e30, false},
+		{"U64", 1 << 63, true},
+		{"U64", 1 << 64, false},
+	}
+	for _, tt := range tests {
+		field := reflect.ValueOf(&dst).Elem().FieldByName(tt.field)
+		err := setField(field, sf, tt.value)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s = %g: got error %v", tt.field, tt.value, err)
+		}
+	}
+}
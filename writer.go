@@ -0,0 +1,1105 @@
+package gospss
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrNoVariables    = errors.New("Dictionary has no variables.")
+	ErrWriterClosed   = errors.New("Writer is closed.")
+	ErrRowLength      = errors.New("Row length does not match the number of variables.")
+	ErrStringTooWide  = errors.New("String variable is wider than 32767 bytes.")
+	ErrNotCompression = errors.New("Unknown compression.")
+	ErrWeight         = errors.New("Weight is not a numeric variable of the dictionary.")
+)
+
+// Values written to the machine floating point record. These are the values
+// IBM SPSS Statistics itself uses on IEEE 754 machines.
+var (
+	sysmisValue  = -math.MaxFloat64
+	highestValue = math.MaxFloat64
+	lowestValue  = math.Nextafter(-math.MaxFloat64, 0)
+)
+
+// The bias used for bytecode compression. Integers between 1 - bias and
+// 251 - bias are stored in the opcode itself.
+const compressionBias = 100
+
+// The number of bytes of bytecode compressed data in each ZLIB block, the
+// same block size IBM SPSS Statistics uses.
+const zBlockSize = 0x3ff000
+
+// A Writer writes cases to an IBM SPSS Statistics encoded system file.
+//
+// As returned by NewWriter, the dictionary has already been written and
+// cases are appended with Write. Close must be called to flush the last
+// block of compressed data. If the underlying writer is an io.WriteSeeker
+// the number of cases in the file header is filled in on Close, otherwise
+// it is left as unknown.
+//
+// ZLIB compressed files need the offset of the trailer in the zlib header
+// in front of the data. If the underlying writer is not an io.WriteSeeker
+// the compressed blocks are kept in memory until Close, see NewWriter.
+type Writer struct {
+	// Byte order of the file, the byte order of the machine unless set
+	// with WithByteOrder.
+	endianess binary.ByteOrder
+	// w is the destination and buf buffers all writes to it.
+	w   io.Writer
+	buf *bufio.Writer
+	// start is the offset of the file header in w, if w is an io.WriteSeeker.
+	start int64
+	// dict is the dictionary being written.
+	dict *Dictionary
+	// vars holds the on disk layout of each variable in the dictionary.
+	vars []*writerVariable
+	// out is where the bytecode compressed data goes, either buf or z.
+	out io.Writer
+	// z splits the bytecode compressed data in to ZLIB compressed blocks.
+	z *zlibWriter
+	// zHeaderOffset is the offset of the zlib header in the file.
+	zHeaderOffset int64
+	// pending holds the compressed blocks of a ZLIB compressed file when
+	// the zlib header can not be written afterwards.
+	pending *bytes.Buffer
+	// opcodes is the current block of bytecode compression opcodes, data
+	// holds the uncompressible elements that follow the block.
+	opcodes []byte
+	data    []byte
+	// ncases is the number of cases written so far.
+	ncases int64
+	closed bool
+}
+
+// writerVariable is the on disk layout of a variable.
+type writerVariable struct {
+	v *Variable
+	// short is the unique, at most 8 bytes long name of the variable record.
+	short string
+	// width is the width of a string variable and 0 for a numeric variable.
+	width int
+	// elements is the number of 8 byte data elements the variable uses.
+	elements int
+	// segments are the widths of the variable records a string is stored
+	// in, more than one for a very long string, and shorts their names.
+	segments []int
+	shorts   []string
+}
+
+// NewWriter writes the dictionary d to w and returns a Writer that
+// writes cases to w.
+//
+// String variables can be up to 32767 bytes wide. Those wider than 255
+// bytes are written as very long strings, split in to segments.
+//
+// With ZLib compression the zlib header in front of the data holds the
+// offset of the trailer that follows it. If w is an io.WriteSeeker each
+// block is written as soon as it is compressed and the header is filled in
+// on Close. Otherwise the whole compressed file is buffered in memory until
+// Close, so give it an io.WriteSeeker, such as an *os.File, for large files.
+func NewWriter(w io.Writer, d *Dictionary, opts ...Option) (*Writer, error) {
+	if len(d.Variables) == 0 {
+		return nil, ErrNoVariables
+	}
+	if d.Compression != Uncompressed && d.Compression != Bytecode && d.Compression != ZLib {
+		return nil, ErrNotCompression
+	}
+	sav := &Writer{
+		endianess: machineEndianess(),
+		w:         w,
+		buf:       bufio.NewWriter(w),
+		dict:      d,
+	}
+	if o := newOptions(opts); o.byteOrder != nil {
+		sav.endianess = o.byteOrder
+	}
+	sav.out = sav.buf
+	_, seekable := w.(io.WriteSeeker)
+	if seekable {
+		sav.start, _ = w.(io.Seeker).Seek(0, io.SeekCurrent)
+	}
+	if d.Weight != nil && !d.Weight.Numeric {
+		return nil, ErrWeight
+	}
+	shorts := make(map[string]bool)
+	for _, v := range d.Variables {
+		wv := &writerVariable{v: v, elements: 1}
+		wv.short = shortName(v.Name, shorts)
+		if !v.Numeric {
+			if v.Width < 1 || v.Width > maxStringWidth {
+				return nil, fmt.Errorf("variable %s: %w", v.Name, ErrStringTooWide)
+			}
+			wv.width = v.Width
+			wv.segments = segmentWidths(v.Width)
+			wv.elements = 0
+			for i, width := range wv.segments {
+				wv.elements += (width + 7) / 8
+				if i == 0 {
+					wv.shorts = append(wv.shorts, wv.short)
+				} else {
+					wv.shorts = append(wv.shorts, shortName(truncate(wv.short, 5), shorts))
+				}
+			}
+		}
+		sav.vars = append(sav.vars, wv)
+	}
+	if d.Weight != nil && sav.weightIndex() == 0 {
+		return nil, ErrWeight
+	}
+	n, err := sav.writeDictionary()
+	if err != nil {
+		return nil, err
+	}
+	if d.Compression == ZLib {
+		sav.zHeaderOffset = n
+		zw := io.Writer(sav.buf)
+		if seekable {
+			// Room for the zlib header, it is written on Close.
+			if _, err := sav.buf.Write(make([]byte, 24)); err != nil {
+				return nil, err
+			}
+		} else {
+			sav.pending = new(bytes.Buffer)
+			zw = sav.pending
+		}
+		sav.z = newZlibWriter(zw, sav.zHeaderOffset)
+		sav.out = sav.z
+	}
+	return sav, nil
+}
+
+// shortName returns a unique variable record name for the variable name.
+// Names that do not fit in 8 bytes are truncated and, if needed, suffixed
+// with a number the way IBM SPSS Statistics does it.
+func shortName(name string, taken map[string]bool) string {
+	base := strings.ToUpper(strings.ReplaceAll(name, " ", "_"))
+	if base == "" {
+		base = "V"
+	}
+	for i := -1; ; i++ {
+		suffix := ""
+		if i >= 0 {
+			suffix = strconv.Itoa(i)
+		}
+		short := truncate(base, 8-len(suffix)) + suffix
+		if !taken[short] {
+			taken[short] = true
+			return short
+		}
+	}
+}
+
+// segmentWidths returns the widths of the segments a string of the width is
+// stored in. Strings of up to 255 bytes are stored in one, very long strings
+// in segments of 255 bytes of which the first 252 are used, and a last one
+// with what remains.
+func segmentWidths(width int) []int {
+	if width <= segmentWidth {
+		return []int{width}
+	}
+	n := (width + segmentLen - 1) / segmentLen
+	widths := make([]int, n)
+	for i := range widths {
+		widths[i] = segmentWidth
+	}
+	widths[n-1] = width - segmentLen*(n-1)
+	return widths
+}
+
+// pad returns the elements of the string value s of the variable. Each
+// segment is padded with spaces to a multiple of 8 bytes.
+func (wv *writerVariable) pad(s string) []byte {
+	s = truncate(s, wv.width)
+	b := make([]byte, 0, wv.elements*8)
+	for i, width := range wv.segments {
+		n := segmentLen
+		if i == len(wv.segments)-1 {
+			n = width
+		}
+		segment := truncate(s, n)
+		s = s[len(segment):]
+		b = append(b, pad(segment, (width+7)/8*8)...)
+	}
+	return b
+}
+
+// truncate returns at most n bytes of s without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// pad returns s truncated or right padded with spaces to n bytes.
+func pad(s string, n int) []byte {
+	b := bytes.Repeat([]byte{' '}, n)
+	copy(b, truncate(s, n))
+	return b
+}
+
+// int32s writes each of the values to b in the byte order of the writer.
+func (w *Writer) int32s(b *bytes.Buffer, values ...int32) {
+	for _, v := range values {
+		binary.Write(b, w.endianess, v)
+	}
+}
+
+// int64s writes each of the values to b in the byte order of the writer.
+func (w *Writer) int64s(b *bytes.Buffer, values ...int64) {
+	for _, v := range values {
+		binary.Write(b, w.endianess, v)
+	}
+}
+
+// flt64s writes each of the values to b in the byte order of the writer.
+func (w *Writer) flt64s(b *bytes.Buffer, values ...float64) {
+	for _, v := range values {
+		binary.Write(b, w.endianess, v)
+	}
+}
+
+// writeDictionary writes every record up to and including the dictionary
+// termination record and returns the number of bytes written.
+func (w *Writer) writeDictionary() (int64, error) {
+	b := new(bytes.Buffer)
+	w.writeFileheader(b)
+	w.writeVariabler(b)
+	w.writeValueLabels(b)
+	w.writeDocuments(b)
+	w.writeMachineIntegerInfo(b)
+	w.writeMachineFloatingPointInfo(b)
+	w.writeVariableDisplay(b)
+	w.writeExtraProductInfo(b)
+	w.writeLongVariableNames(b)
+	w.writeVeryLongStrings(b)
+	w.writeAttributes(b)
+	w.writeMRSets(b)
+	w.writeCharacterEncoding(b)
+	w.writeLongStringValueLabels(b)
+	w.writeLongStringMissingValues(b)
+	w.writeRawRecords(b)
+	// Dictionary termination.
+	w.int32s(b, 999, 0)
+	n, err := w.buf.Write(b.Bytes())
+	return int64(n), err
+}
+
+// weightIndex returns the dictionary index of the weight variable plus 1, or
+// 0 if there is none.
+func (w *Writer) weightIndex() int32 {
+	index := 1
+	for _, wv := range w.vars {
+		if wv.v == w.dict.Weight {
+			return int32(index)
+		}
+		index += wv.elements
+	}
+	return 0
+}
+
+// writeFileheader writes the file header with an unknown number of cases.
+func (w *Writer) writeFileheader(b *bytes.Buffer) {
+	var nominalCaseSize int32
+	for _, wv := range w.vars {
+		nominalCaseSize += int32(wv.elements)
+	}
+	created := w.dict.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	product := w.dict.Product
+	if product == "" {
+		product = "SPSS DATA FILE gospss"
+	}
+	if w.dict.Compression == ZLib {
+		b.WriteString("$FL3")
+	} else {
+		b.WriteString("$FL2")
+	}
+	b.Write(pad("@(#) "+product, 60))
+	w.int32s(b, 2, nominalCaseSize, int32(w.dict.Compression), w.weightIndex(), -1)
+	w.flt64s(b, compressionBias)
+	b.Write(pad(created.Format("02 Jan 06"), 9))
+	b.Write(pad(created.Format("15:04:05"), 8))
+	b.Write(pad(w.dict.FileLabel, 64))
+	b.Write(make([]byte, 3))
+}
+
+// printwrite packs a print or write format in to an int32.
+func printwrite(tpe, width, decimal int) int32 {
+	return int32(tpe)<<16 | int32(width)<<8 | int32(decimal)
+}
+
+// writeVariabler writes a variable record for every variable, and the
+// continuation records of strings wider than 8 bytes.
+func (w *Writer) writeVariabler(b *bytes.Buffer) {
+	for _, wv := range w.vars {
+		v := wv.v
+		printFormat, writeFormat := wv.formats()
+		nMissing, missing := w.missingValues(wv)
+		var hasVarLabel int32
+		if v.Label != "" {
+			hasVarLabel = 1
+		}
+		segments := wv.segments
+		if v.Numeric {
+			segments = []int{0}
+		}
+		for i, width := range segments {
+			short := wv.short
+			if i > 0 {
+				// Only the first segment of a very long string has a label
+				// and the segments are displayed at their own width.
+				short = wv.shorts[i]
+				hasVarLabel, nMissing, missing = 0, 0, nil
+			}
+			if len(segments) > 1 {
+				printFormat = Format{Type: FormatA, Width: width}
+				writeFormat = printFormat
+			}
+			w.int32s(b, 2, int32(width), hasVarLabel, nMissing, printFormat.code(), writeFormat.code())
+			b.Write(pad(short, 8))
+			if hasVarLabel == 1 {
+				label := truncate(v.Label, 255)
+				w.int32s(b, int32(len(label)))
+				b.Write(pad(label, (len(label)+3)/4*4))
+			}
+			b.Write(missing)
+			for j := 1; j < (width+7)/8; j++ {
+				w.int32s(b, 2, -1, 0, 0, printwrite(1, 29, 1), printwrite(1, 29, 1))
+				b.Write(pad("", 8))
+			}
+		}
+	}
+}
+
+// writeVeryLongStrings writes the very long string record for the strings
+// wider than 255 bytes.
+func (w *Writer) writeVeryLongStrings(b *bytes.Buffer) {
+	var pairs []byte
+	for _, wv := range w.vars {
+		if len(wv.segments) > 1 {
+			pairs = append(pairs, fmt.Sprintf("%s=%05d\x00\t", wv.short, wv.width)...)
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	w.int32s(b, 7, 14, 1, int32(len(pairs)))
+	b.Write(pairs)
+}
+
+// missingValues returns the number of missing values of a variable as
+// stored in the variable record, negative for a range, and the missing value
+// elements. Strings wider than 8 bytes have their missing values in the long
+// string missing values record instead.
+func (w *Writer) missingValues(wv *writerVariable) (int32, []byte) {
+	m := wv.v.Missing
+	b := new(bytes.Buffer)
+	if !wv.v.Numeric {
+		if wv.width > 8 {
+			return 0, nil
+		}
+		strs := m.Strings
+		if len(strs) > 3 {
+			strs = strs[:3]
+		}
+		for _, s := range strs {
+			b.Write(pad(truncate(s, 8), 8))
+		}
+		return int32(len(strs)), b.Bytes()
+	}
+	values := m.Values
+	if m.Range {
+		if len(values) > 1 {
+			values = values[:1]
+		}
+		low, high := m.Low, m.High
+		if math.IsInf(low, -1) {
+			low = lowestValue
+		}
+		if math.IsInf(high, 1) {
+			high = highestValue
+		}
+		w.flt64s(b, low, high)
+		w.flt64s(b, values...)
+		return -2 - int32(len(values)), b.Bytes()
+	}
+	if len(values) > 3 {
+		values = values[:3]
+	}
+	w.flt64s(b, values...)
+	return int32(len(values)), b.Bytes()
+}
+
+// writeLongStringMissingValues writes the long string missing values record
+// for the strings wider than 8 bytes that have missing values. Only the
+// first 8 bytes of each value are stored.
+func (w *Writer) writeLongStringMissingValues(b *bytes.Buffer) {
+	body := new(bytes.Buffer)
+	for _, wv := range w.vars {
+		strs := wv.v.Missing.Strings
+		if wv.v.Numeric || wv.width <= 8 || len(strs) == 0 {
+			continue
+		}
+		if len(strs) > 3 {
+			strs = strs[:3]
+		}
+		name := truncate(wv.v.Name, 64)
+		w.int32s(body, int32(len(name)))
+		body.WriteString(name)
+		body.WriteByte(byte(len(strs)))
+		w.int32s(body, 8)
+		for _, s := range strs {
+			body.Write(pad(truncate(s, 8), 8))
+		}
+	}
+	if body.Len() == 0 {
+		return
+	}
+	w.int32s(b, 7, 22, 1, int32(body.Len()))
+	b.Write(body.Bytes())
+}
+
+// formats returns the print and write formats of the variable. Without a
+// print format one is made from Type, Width and Decimal, or F8 and A of the
+// string width if those are not set either. Without a write format the
+// print format is used.
+func (wv *writerVariable) formats() (Format, Format) {
+	v := wv.v
+	printFormat := v.Print
+	if printFormat.Type == 0 {
+		if v.Numeric {
+			printFormat = Format{Type: FormatType(v.Type), Width: v.Width, Decimals: v.Decimal}
+			if printFormat.Type == 0 {
+				printFormat.Type = FormatF
+			}
+			if printFormat.Width == 0 {
+				printFormat.Width = 8
+			}
+		} else {
+			printFormat = Format{Type: FormatA, Width: wv.width}
+		}
+	}
+	writeFormat := v.Write
+	if writeFormat.Type == 0 {
+		writeFormat = printFormat
+	}
+	return printFormat, writeFormat
+}
+
+// writeValueLabels writes a value label record and a value label variables
+// record for each variable with value labels.
+func (w *Writer) writeValueLabels(b *bytes.Buffer) {
+	index := 1
+	for _, wv := range w.vars {
+		v := wv.v
+		if len(v.ValueLabels) > 0 && wv.width <= 8 {
+			w.int32s(b, 3, int32(len(v.ValueLabels)))
+			for _, vl := range v.ValueLabels {
+				if v.Numeric {
+					w.flt64s(b, numericKey(vl.Key))
+				} else {
+					b.Write(pad(fmt.Sprint(vl.Key), 8))
+				}
+				label := truncate(vl.Value, 255)
+				b.WriteByte(byte(len(label)))
+				b.Write(pad(label, int(calcLen(len(label), 8))))
+			}
+			w.int32s(b, 4, 1, int32(index))
+		}
+		index += wv.elements
+	}
+}
+
+// writeLongStringValueLabels writes the long string value labels record for
+// the strings wider than 8 bytes that have value labels.
+func (w *Writer) writeLongStringValueLabels(b *bytes.Buffer) {
+	body := new(bytes.Buffer)
+	for _, wv := range w.vars {
+		v := wv.v
+		if v.Numeric || wv.width <= 8 || len(v.ValueLabels) == 0 {
+			continue
+		}
+		name := truncate(v.Name, 64)
+		w.int32s(body, int32(len(name)))
+		body.WriteString(name)
+		w.int32s(body, int32(wv.width), int32(len(v.ValueLabels)))
+		for _, vl := range v.ValueLabels {
+			w.int32s(body, int32(wv.width))
+			body.Write(pad(truncate(fmt.Sprint(vl.Key), wv.width), wv.width))
+			label := truncate(vl.Value, 120)
+			w.int32s(body, int32(len(label)))
+			body.WriteString(label)
+		}
+	}
+	if body.Len() == 0 {
+		return
+	}
+	w.int32s(b, 7, 21, 1, int32(body.Len()))
+	b.Write(body.Bytes())
+}
+
+// writtenSubtypes are the subtypes of the extension records a Writer writes
+// itself, which are left out when writing the raw records of a dictionary.
+var writtenSubtypes = map[int32]bool{
+	3: true, 4: true, 7: true, 10: true, 11: true, 13: true, 14: true, 17: true, 18: true,
+	19: true, 20: true, 21: true, 22: true,
+}
+
+// writeRawRecords writes the raw extension records of the dictionary.
+func (w *Writer) writeRawRecords(b *bytes.Buffer) {
+	for _, raw := range w.dict.RawRecords {
+		if writtenSubtypes[raw.Subtype] {
+			continue
+		}
+		// The header is made from Data so that it always matches it.
+		size, count := raw.Size, int32(len(raw.Data))
+		if size > 0 {
+			count /= size
+		} else {
+			size = 1
+		}
+		w.int32s(b, 7, raw.Subtype, size, count)
+		data := raw.Data[:size*count]
+		if raw.ByteOrder != nil && raw.ByteOrder != w.endianess && (size == 2 || size == 4 || size == 8) {
+			data = reverseElements(data, int(size))
+		}
+		b.Write(data)
+	}
+}
+
+// reverseElements returns a copy of data with the bytes of each element of
+// size bytes reversed, which converts numbers to the other byte order.
+func reverseElements(data []byte, size int) []byte {
+	reversed := make([]byte, len(data))
+	for i := 0; i < len(data); i += size {
+		for j := 0; j < size; j++ {
+			reversed[i+j] = data[i+size-1-j]
+		}
+	}
+	return reversed
+}
+
+// numericKey converts the key of a numeric value label to a float64.
+func numericKey(key interface{}) float64 {
+	switch k := key.(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(k), 64)
+		if err != nil {
+			return sysmisValue
+		}
+		return f
+	default:
+		f, err := toFloat64(key)
+		if err != nil {
+			return sysmisValue
+		}
+		return f
+	}
+}
+
+// writeDocuments writes the document record, if there are documents.
+func (w *Writer) writeDocuments(b *bytes.Buffer) {
+	if len(w.dict.Documents) == 0 {
+		return
+	}
+	w.int32s(b, 6, int32(len(w.dict.Documents)))
+	for _, line := range w.dict.Documents {
+		b.Write(pad(line, documentLineLen))
+	}
+}
+
+// writeExtraProductInfo writes the extra product info record, if there is
+// product info.
+func (w *Writer) writeExtraProductInfo(b *bytes.Buffer) {
+	if w.dict.ProductInfo == "" {
+		return
+	}
+	w.int32s(b, 7, 10, 1, int32(len(w.dict.ProductInfo)))
+	b.WriteString(w.dict.ProductInfo)
+}
+
+// writeMachineIntegerInfo writes the machine integer record.
+func (w *Writer) writeMachineIntegerInfo(b *bytes.Buffer) {
+	endianess := int32(2)
+	if w.endianess == binary.BigEndian {
+		endianess = 1
+	}
+	w.int32s(b, 7, 3, 4, 8, 1, 0, 0, -1, 1, 1, endianess, 65001)
+}
+
+// writeMachineFloatingPointInfo writes the machine floating point record.
+func (w *Writer) writeMachineFloatingPointInfo(b *bytes.Buffer) {
+	w.int32s(b, 7, 4, 8, 3)
+	w.flt64s(b, sysmisValue, highestValue, lowestValue)
+}
+
+// writeVariableDisplay writes the variable display parameters record, with
+// a set of parameters for each segment of a variable. Variables without a
+// Measure get the defaults of IBM SPSS Statistics: numeric variables are
+// scale and right aligned, strings nominal and left aligned.
+func (w *Writer) writeVariableDisplay(b *bytes.Buffer) {
+	var values []int32
+	for _, wv := range w.vars {
+		v := wv.v
+		measure, width, alignment := v.Measure, v.DisplayWidth, v.Alignment
+		if measure == MeasureUnknown {
+			measure, alignment = MeasureScale, AlignRight
+			if !v.Numeric {
+				measure, alignment = MeasureNominal, AlignLeft
+			}
+		}
+		if width == 0 {
+			printFormat, _ := wv.formats()
+			width = printFormat.Width
+			if width > 32 {
+				width = 32
+			}
+		}
+		for i := 0; i < len(wv.segments) || i == 0; i++ {
+			values = append(values, int32(measure), int32(width), int32(alignment))
+		}
+	}
+	w.int32s(b, 7, 11, 4, int32(len(values)))
+	w.int32s(b, values...)
+}
+
+// writeLongVariableNames writes the long variable names record.
+func (w *Writer) writeLongVariableNames(b *bytes.Buffer) {
+	var pairs []string
+	for _, wv := range w.vars {
+		pairs = append(pairs, wv.short+"="+truncate(wv.v.Name, 64))
+	}
+	names := strings.Join(pairs, "\t")
+	w.int32s(b, 7, 13, 1, int32(len(names)))
+	b.WriteString(names)
+}
+
+// writeAttributes writes the data file attributes record, if the file has
+// attributes, and the variable attributes record with the attributes and
+// role of every variable.
+func (w *Writer) writeAttributes(b *bytes.Buffer) {
+	if len(w.dict.Attributes) > 0 {
+		attributes := formatAttributes(w.dict.Attributes)
+		w.int32s(b, 7, 17, 1, int32(len(attributes)))
+		b.WriteString(attributes)
+	}
+	var variables []string
+	for _, wv := range w.vars {
+		variables = append(variables, truncate(wv.v.Name, 64)+":"+formatAttributes(variableAttributes(wv.v)))
+	}
+	attributes := strings.Join(variables, "/")
+	w.int32s(b, 7, 18, 1, int32(len(attributes)))
+	b.WriteString(attributes)
+}
+
+// writeMRSets writes the multiple response sets, the dichotomy sets with
+// CATEGORYLABELS=COUNTEDVALUES in a record of their own as IBM SPSS
+// Statistics before version 14 does not understand them.
+func (w *Writer) writeMRSets(b *bytes.Buffer) {
+	shorts := make(map[*Variable]string, len(w.vars))
+	for _, wv := range w.vars {
+		shorts[wv.v] = wv.short
+	}
+	var old, counted strings.Builder
+	for _, set := range w.dict.MRSets {
+		if set.Kind == MRDichotomyCounted {
+			counted.WriteString(formatMRSet(set, shorts))
+		} else {
+			old.WriteString(formatMRSet(set, shorts))
+		}
+	}
+	if old.Len() > 0 {
+		w.int32s(b, 7, 7, 1, int32(old.Len()))
+		b.WriteString(old.String())
+	}
+	if counted.Len() > 0 {
+		w.int32s(b, 7, 19, 1, int32(counted.Len()))
+		b.WriteString(counted.String())
+	}
+}
+
+// writeCharacterEncoding writes the character encoding record. Strings in
+// Go are UTF-8 so that is what is written.
+func (w *Writer) writeCharacterEncoding(b *bytes.Buffer) {
+	w.int32s(b, 7, 20, 1, 5)
+	b.WriteString("UTF-8")
+}
+
+// toFloat64 converts a numeric case value to a float64. A nil value is
+// converted to the system missing value.
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return math.NaN(), nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case time.Time:
+		return TimeToSPSS(v), nil
+	case time.Duration:
+		return DurationToSPSS(v), nil
+	case time.Weekday:
+		return float64(v + 1), nil
+	case time.Month:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("can't write %T as a numeric value", value)
+	}
+}
+
+// toString converts a string case value to a string. A nil value is
+// converted to an empty string.
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("can't write %T as a string value", value)
+	}
+}
+
+// Write writes a single case to w. The row must have one value for every
+// variable in the dictionary. Numeric variables accept any Go integer or
+// floating point type, NaN and nil are written as system missing. They also
+// accept the types returned by a Reader using WithDates, which are
+// converted with TimeToSPSS and DurationToSPSS. String variables accept a
+// string or a []byte, longer values are truncated.
+func (w *Writer) Write(row Row) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if len(row) != len(w.vars) {
+		return ErrRowLength
+	}
+	// The whole row is converted before any of it is written, as a value
+	// that can't be converted would otherwise leave part of the case in
+	// the data and misalign the ones after it.
+	values := make([]interface{}, len(row))
+	for i, wv := range w.vars {
+		var err error
+		if wv.v.Numeric {
+			values[i], err = toFloat64(row[i])
+		} else {
+			values[i], err = toString(row[i])
+		}
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", wv.v.Name, err)
+		}
+	}
+	for i, wv := range w.vars {
+		if wv.v.Numeric {
+			if err := w.writeNumeric(values[i].(float64)); err != nil {
+				return err
+			}
+		} else {
+			if err := w.writeString(wv.pad(values[i].(string))); err != nil {
+				return err
+			}
+		}
+	}
+	w.ncases++
+	return nil
+}
+
+// WriteAll writes all rows to w and closes it.
+func (w *Writer) WriteAll(rows []Row) error {
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// writeNumeric writes a single numeric element.
+func (w *Writer) writeNumeric(f float64) error {
+	if math.IsNaN(f) {
+		f = sysmisValue
+	}
+	if w.dict.Compression == Uncompressed {
+		return binary.Write(w.buf, w.endianess, f)
+	}
+	switch {
+	case f == sysmisValue:
+		return w.writeOpcode(255, nil)
+	case f == math.Trunc(f) && f >= 1-compressionBias && f <= 251-compressionBias:
+		return w.writeOpcode(byte(f+compressionBias), nil)
+	default:
+		b := make([]byte, 8)
+		w.endianess.PutUint64(b, math.Float64bits(f))
+		return w.writeOpcode(253, b)
+	}
+}
+
+// writeString writes the elements of a string value, s is padded to a
+// multiple of 8 bytes.
+func (w *Writer) writeString(s []byte) error {
+	if w.dict.Compression == Uncompressed {
+		_, err := w.buf.Write(s)
+		return err
+	}
+	for i := 0; i < len(s); i += 8 {
+		chunk := s[i : i+8]
+		if bytes.Equal(chunk, []byte("        ")) {
+			if err := w.writeOpcode(254, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.writeOpcode(253, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOpcode adds a bytecode compression opcode to the current block, and
+// the data element that follows it if the opcode is 253. Full blocks are
+// written together with their data elements.
+func (w *Writer) writeOpcode(opcode byte, data []byte) error {
+	w.opcodes = append(w.opcodes, opcode)
+	w.data = append(w.data, data...)
+	if len(w.opcodes) < 8 {
+		return nil
+	}
+	return w.flushOpcodes()
+}
+
+// flushOpcodes writes the current block of opcodes padded with 0 opcodes,
+// followed by its data elements.
+func (w *Writer) flushOpcodes() error {
+	if len(w.opcodes) == 0 {
+		return nil
+	}
+	for len(w.opcodes) < 8 {
+		w.opcodes = append(w.opcodes, 0)
+	}
+	if _, err := w.out.Write(w.opcodes); err != nil {
+		return err
+	}
+	if _, err := w.out.Write(w.data); err != nil {
+		return err
+	}
+	w.opcodes = w.opcodes[:0]
+	w.data = w.data[:0]
+	return nil
+}
+
+// Close flushes any buffered data to the underlying writer and, if possible,
+// updates the number of cases in the file header. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.flushOpcodes(); err != nil {
+		return err
+	}
+	if w.z != nil {
+		if err := w.closeZLib(); err != nil {
+			return err
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	return w.writeNCases()
+}
+
+// closeZLib compresses the last block and writes the zlib header, the
+// pending blocks and the trailer.
+func (w *Writer) closeZLib() error {
+	if err := w.z.Close(); err != nil {
+		return err
+	}
+	trailer := new(bytes.Buffer)
+	w.int64s(trailer, -compressionBias, 0)
+	w.int32s(trailer, int32(w.z.blockSize), int32(len(w.z.blocks)))
+	for _, block := range w.z.blocks {
+		w.int64s(trailer, block.unCompressedOffset, block.compressedOffset)
+		w.int32s(trailer, block.unCompressedSize, block.compressedSize)
+	}
+	header := new(bytes.Buffer)
+	w.int64s(header, w.zHeaderOffset, w.z.offset, int64(trailer.Len()))
+
+	if w.pending != nil {
+		if _, err := w.buf.Write(header.Bytes()); err != nil {
+			return err
+		}
+		if _, err := w.buf.Write(w.pending.Bytes()); err != nil {
+			return err
+		}
+		_, err := w.buf.Write(trailer.Bytes())
+		return err
+	}
+	if _, err := w.buf.Write(trailer.Bytes()); err != nil {
+		return err
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	return w.patch(w.zHeaderOffset, header.Bytes())
+}
+
+// writeNCases updates the number of cases in the file header if the
+// underlying writer is an io.WriteSeeker.
+func (w *Writer) writeNCases() error {
+	if _, ok := w.w.(io.WriteSeeker); !ok || w.ncases > math.MaxInt32 {
+		return nil
+	}
+	b := new(bytes.Buffer)
+	w.int32s(b, int32(w.ncases))
+	// The number of cases follows the record type, product name, layout
+	// code, nominal case size, compression and weight index.
+	return w.patch(80, b.Bytes())
+}
+
+// patch overwrites the bytes at offset in the file with b. The underlying
+// writer must be an io.WriteSeeker and everything must have been flushed.
+func (w *Writer) patch(offset int64, b []byte) error {
+	s := w.w.(io.WriteSeeker)
+	end, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Seek(w.start+offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := s.Write(b); err != nil {
+		return err
+	}
+	_, err = s.Seek(end, io.SeekStart)
+	return err
+}
+
+// zlibWriter splits the bytecode compressed data in to blocks and writes
+// each block as a ZLIB stream of its own.
+type zlibWriter struct {
+	// w receives the compressed blocks.
+	w io.Writer
+	// blockSize is the number of uncompressed bytes in each block.
+	blockSize int
+	// block is the uncompressed data of the current block.
+	block []byte
+	// compressed is scratch space for compressing a block.
+	compressed bytes.Buffer
+	zw         *zlib.Writer
+	// offset and unCompressedOffset are the offsets of the next block in the
+	// file, compressed and as if it was only bytecode compressed.
+	offset             int64
+	unCompressedOffset int64
+	// blocks describes the blocks written so far.
+	blocks []*zLibBlock
+}
+
+// newZlibWriter returns a zlibWriter for a zlib header at zHeaderOffset.
+func newZlibWriter(w io.Writer, zHeaderOffset int64) *zlibWriter {
+	z := &zlibWriter{
+		w:                  w,
+		blockSize:          zBlockSize,
+		offset:             zHeaderOffset + 24,
+		unCompressedOffset: zHeaderOffset,
+	}
+	z.zw = zlib.NewWriter(&z.compressed)
+	return z
+}
+
+// Write adds p to the current block, compressing every block that fills up.
+func (z *zlibWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := z.blockSize - len(z.block)
+		if free > len(p) {
+			free = len(p)
+		}
+		z.block = append(z.block, p[:free]...)
+		p = p[free:]
+		if len(z.block) == z.blockSize {
+			if err := z.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush compresses and writes the current block.
+func (z *zlibWriter) flush() error {
+	if len(z.block) == 0 {
+		return nil
+	}
+	z.compressed.Reset()
+	z.zw.Reset(&z.compressed)
+	if _, err := z.zw.Write(z.block); err != nil {
+		return err
+	}
+	if err := z.zw.Close(); err != nil {
+		return err
+	}
+	if _, err := z.w.Write(z.compressed.Bytes()); err != nil {
+		return err
+	}
+	z.blocks = append(z.blocks, &zLibBlock{
+		unCompressedOffset: z.unCompressedOffset,
+		compressedOffset:   z.offset,
+		unCompressedSize:   int32(len(z.block)),
+		compressedSize:     int32(z.compressed.Len()),
+	})
+	z.unCompressedOffset += int64(len(z.block))
+	z.offset += int64(z.compressed.Len())
+	z.block = z.block[:0]
+	return nil
+}
+
+// Close compresses and writes the last, possibly partial, block.
+func (z *zlibWriter) Close() error {
+	return z.flush()
+}
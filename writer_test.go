@@ -0,0 +1,336 @@
+package gospss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCreated = time.Date(2016, time.November, 28, 18, 18, 41, 0, time.UTC)
+
+func testDictionary(compression Compression) *Dictionary {
+	d := &Dictionary{
+		FileLabel:   "gospss test file",
+		Created:     testCreated,
+		Compression: compression,
+		Variables: []*Variable{
+			{Name: "RespondentID", Label: "Respondent", Numeric: true, Width: 8},
+			{Name: "Score", Label: "Satisfaction score", Numeric: true, Width: 8, Decimal: 2, ValueLabels: []*ValueLabel{
+				{Key: 1.0, Value: "Low"},
+				{Key: 2.0, Value: "High"},
+			}},
+			{Name: "City", Label: "City of residence", Width: 20},
+			{Name: "Code", Width: 4},
+		},
+	}
+	d.Weight = d.Variables[1]
+	return d
+}
+
+var testRows = []Row{
+	{1.0, 1.0, "Stockholm", "A"},
+	{2.0, 2.5, "Rio de Janeiro city", ""},
+	{3.0, math.NaN(), "", "BCDE"},
+	{-99.0, 1234567.891, "Oslo", "F"},
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	for _, compression := range []Compression{Uncompressed, Bytecode, ZLib} {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, testDictionary(compression))
+		if err != nil {
+			t.Fatalf("compression %d: failed to create writer ::: err >>> %s", compression, err)
+		}
+		if err := w.WriteAll(testRows); err != nil {
+			t.Fatalf("compression %d: failed to write rows ::: err >>> %s", compression, err)
+		}
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("compression %d: failed to read ::: err >>> %s", compression, err)
+		}
+		d := r.Dictionary()
+		if d.FileLabel != "gospss test file" || d.Compression != compression || !d.Created.Equal(testCreated) ||
+			d.Product != "SPSS DATA FILE gospss" || d.NumCases != -1 || d.Weight == nil || d.Weight.Name != "Score" {
+			t.Errorf("compression %d: unexpected dictionary %+v", compression, d)
+		}
+		variables := r.MetaData()
+		if len(variables) != 4 {
+			t.Fatalf("compression %d: got %d variables, want 4", compression, len(variables))
+		}
+		if variables[0].Name != "RespondentID" || variables[2].Label != "City of residence" || variables[2].Width != 20 {
+			t.Errorf("compression %d: unexpected variables %+v %+v", compression, variables[0], variables[2])
+		}
+		if len(variables[1].ValueLabels) != 2 || variables[1].ValueLabels[1].Value != "High" {
+			t.Errorf("compression %d: unexpected value labels %v", compression, variables[1].ValueLabels)
+		}
+		if variables[0].Measure != MeasureScale || variables[0].Alignment != AlignRight ||
+			variables[2].Measure != MeasureNominal || variables[2].DisplayWidth != 20 || variables[2].Alignment != AlignLeft {
+			t.Errorf("compression %d: unexpected display parameters %+v %+v", compression, variables[0], variables[2])
+		}
+
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("compression %d: failed to read all records ::: err >>> %s", compression, err)
+		}
+		if len(rows) != len(testRows) {
+			t.Fatalf("compression %d: got %d rows, want %d", compression, len(rows), len(testRows))
+		}
+		for i, row := range rows {
+			for j, value := range row {
+				want := testRows[i][j]
+				if f, ok := want.(float64); ok && math.IsNaN(f) {
+					if !math.IsNaN(value.(float64)) {
+						t.Errorf("compression %d: row %d column %d got %v, want NaN", compression, i, j, value)
+					}
+					continue
+				}
+				if value != want {
+					t.Errorf("compression %d: row %d column %d got %v, want %v", compression, i, j, value, want)
+				}
+			}
+		}
+	}
+}
+
+func TestWriterZLibBlocks(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "*.zsav")
+	if err != nil {
+		t.Fatalf("failed to create file ::: err >>> %s", err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, testDictionary(ZLib))
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	// Small blocks so the cases span several of them.
+	w.z.blockSize = 64
+	for i := 0; i < 500; i++ {
+		if err := w.Write(Row{float64(i), 1000.5 + float64(i), "Stockholm", "A"}); err != nil {
+			t.Fatalf("failed to write row %d ::: err >>> %s", i, err)
+		}
+	}
+	// The blocks go to the file as they fill up, in front of room left for
+	// the zlib header.
+	if fi, err := f.Stat(); err != nil || fi.Size() <= w.zHeaderOffset+24 || w.pending != nil {
+		t.Errorf("blocks were not written before Close ::: err >>> %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer ::: err >>> %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	h := r.header
+	if h.Fileheader.ncases != 500 {
+		t.Errorf("got %d cases in the file header, want 500", h.Fileheader.ncases)
+	}
+	if h.ZLibDataTrailer.nBlocks < 2 || int(h.ZLibDataTrailer.nBlocks) != len(h.ZLibDataTrailer.blocks) {
+		t.Errorf("got %d blocks, want several", h.ZLibDataTrailer.nBlocks)
+	}
+	first := h.ZLibDataTrailer.blocks[0]
+	if first.unCompressedOffset != h.ZLibDataHeader.zHeaderOffset || first.compressedOffset != h.ZLibDataHeader.zHeaderOffset+24 {
+		t.Errorf("unexpected first block %+v for zlib header %+v", first, h.ZLibDataHeader)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if len(rows) != 500 || rows[499][0] != 499.0 || rows[499][1] != 1499.5 || rows[499][2] != "Stockholm" {
+		t.Errorf("got %d rows, last %v", len(rows), rows[len(rows)-1])
+	}
+}
+
+func TestWriterBadValue(t *testing.T) {
+	for _, compression := range []Compression{Uncompressed, Bytecode, ZLib} {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, testDictionary(compression))
+		if err != nil {
+			t.Fatalf("compression %d: failed to create writer ::: err >>> %s", compression, err)
+		}
+		if err := w.Write(testRows[0]); err != nil {
+			t.Fatalf("compression %d: failed to write row ::: err >>> %s", compression, err)
+		}
+		// The numbers before the bad value must not be written.
+		if err := w.Write(Row{5.0, 300.5, 42, "X"}); err == nil {
+			t.Errorf("compression %d: wrote an int as a string value", compression)
+		}
+		if err := w.WriteAll(testRows[1:]); err != nil {
+			t.Fatalf("compression %d: failed to write rows ::: err >>> %s", compression, err)
+		}
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("compression %d: failed to read ::: err >>> %s", compression, err)
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("compression %d: failed to read all records ::: err >>> %s", compression, err)
+		}
+		if len(rows) != len(testRows) || rows[1][0] != 2.0 || rows[1][2] != "Rio de Janeiro city" || rows[3][3] != "F" {
+			t.Errorf("compression %d: got rows %v", compression, rows)
+		}
+	}
+}
+
+func TestByteOrderDetection(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, testDictionary(Bytecode), WithByteOrder(order))
+		if err != nil {
+			t.Fatalf("%s: failed to create writer ::: err >>> %s", order, err)
+		}
+		if err := w.WriteAll(testRows); err != nil {
+			t.Fatalf("%s: failed to write rows ::: err >>> %s", order, err)
+		}
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to read ::: err >>> %s", order, err)
+		}
+		if r.endianess != order {
+			t.Errorf("%s: detected %s", order, r.endianess)
+		}
+		v := r.MetaData()[1]
+		if v.Name != "Score" || v.Width != 8 || v.Decimal != 2 || len(v.ValueLabels) != 2 {
+			t.Errorf("%s: unexpected variable %+v", order, v)
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: failed to read all records ::: err >>> %s", order, err)
+		}
+		if len(rows) != len(testRows) || rows[3][1] != 1234567.891 {
+			t.Errorf("%s: got rows %v", order, rows)
+		}
+	}
+}
+
+func TestMachineIntegerEndianess(t *testing.T) {
+	other := otherEndianess(machineEndianess())
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, testDictionary(Bytecode), WithByteOrder(other))
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll(testRows); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	file := buf.Bytes()
+	record := new(bytes.Buffer)
+	for _, n := range machineIntegerInfoRecord {
+		binary.Write(record, other, n)
+	}
+	// The endianness follows the record header and 6 other fields.
+	endianess := bytes.Index(file, record.Bytes()) + 40
+
+	// A layout code that makes sense in neither byte order leaves the one
+	// of the machine integer record.
+	unknown := append([]byte(nil), file...)
+	copy(unknown[64:68], []byte{9, 0, 0, 9})
+	r, err := NewReader(bytes.NewReader(unknown))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if r.endianess != other {
+		t.Errorf("detected %s, want %s", r.endianess, other)
+	}
+	if rows, err := r.ReadAll(); err != nil || len(rows) != len(testRows) || rows[3][1] != 1234567.891 {
+		t.Errorf("got rows %v, error %v", rows, err)
+	}
+
+	// The machine integer record has to agree with the layout code.
+	mismatch := append([]byte(nil), file...)
+	other.PutUint32(mismatch[endianess:], 3-other.Uint32(file[endianess:]))
+	if _, err := NewReader(bytes.NewReader(mismatch)); err != ErrByteOrder {
+		t.Errorf("got error %v, want %v", err, ErrByteOrder)
+	}
+	if _, err := NewReader(bytes.NewReader(mismatch), WithByteOrder(other)); err != nil {
+		t.Errorf("failed to read with the byte order forced ::: err >>> %s", err)
+	}
+}
+
+func TestWriterDocuments(t *testing.T) {
+	d := testDictionary(Bytecode)
+	d.ProductInfo = "Exported by the panel pipeline"
+	d.AddDocument("Wave 3 of the panel.\nWeighted by Score.")
+	d.AddDocument(strings.Repeat("x", 100))
+	if len(d.Documents) != 4 || len(d.Documents[2]) != 80 || len(d.Documents[3]) != 20 {
+		t.Fatalf("unexpected documents %q", d.Documents)
+	}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer ::: err >>> %s", err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	got := r.Dictionary()
+	if got.ProductInfo != d.ProductInfo {
+		t.Errorf("got product info %q, want %q", got.ProductInfo, d.ProductInfo)
+	}
+	if strings.Join(got.Documents, "|") != strings.Join(d.Documents, "|") {
+		t.Errorf("got documents %q, want %q", got.Documents, d.Documents)
+	}
+
+	// Documents of a file that is read can be appended to.
+	got.AddDocument("Checked.")
+	buf.Reset()
+	if w, err = NewWriter(buf, got); err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer ::: err >>> %s", err)
+	}
+	if r, err = NewReader(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	if docs := r.Dictionary().Documents; len(docs) != 5 || docs[4] != "Checked." {
+		t.Errorf("got documents %q", docs)
+	}
+}
+
+func TestWriteLongStringMissingValues(t *testing.T) {
+	d := &Dictionary{Variables: []*Variable{
+		{Name: "CityOfResidence", Width: 20, Missing: MissingSpec{Strings: []string{"NA"}}},
+	}}
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, d)
+	if err != nil {
+		t.Fatalf("failed to create writer ::: err >>> %s", err)
+	}
+	if err := w.WriteAll([]Row{{"Stockholm"}, {"NA"}}); err != nil {
+		t.Fatalf("failed to write rows ::: err >>> %s", err)
+	}
+	// The record names the variable by its long name, not CITYOFRE.
+	if !bytes.Contains(buf.Bytes(), []byte("\x0f\x00\x00\x00CityOfResidence\x01\x08\x00\x00\x00NA      ")) {
+		t.Errorf("file does not contain the missing values of CityOfResidence")
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), WithMissing(MissingAsNaN))
+	if err != nil {
+		t.Fatalf("failed to read ::: err >>> %s", err)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all records ::: err >>> %s", err)
+	}
+	if got, want := fmt.Sprint(rows), "[[Stockholm] [<nil>]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}